@@ -3,10 +3,8 @@ package config
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"reflect"
-	"strconv"
 	"strings"
 )
 
@@ -14,10 +12,10 @@ type (
 	// Config represents an app configuration.
 	Config struct {
 		// Debug
-		Debug bool `json:"debug"`
+		Debug bool `json:"debug" desc:"enable debug mode"`
 
 		// ConfigFilePath stores a file path where the config was read from.
-		ConfigFilePath string `json:"config_filepath"`
+		ConfigFilePath string `json:"config_filepath" flag:"-"`
 	}
 )
 
@@ -34,79 +32,170 @@ func NewConfig(debug bool) *Config {
 }
 
 // NewConfigFromFile returns a Config object read from a filename.
+// It panics on errors; use `LoadFile()` to handle them.
 func NewConfigFromFile(filename string) *Config {
+	config, err := LoadFile(filename)
+	if err != nil {
+		panic(err)
+	}
+
+	return config
+}
+
+// LoadFile returns a Config object read from a filename. An empty
+// filename returns a copy of `DefaultConfig`. The format is detected
+// like in `WithFile()`. Parse errors are reported as `Errors` with
+// a line of the problem.
+func LoadFile(filename string) (*Config, error) {
 	config := DefaultConfig
 
-	if filename == "" {
-		return &config
+	if err := Load(&config, WithFile(filename), WithEnv(false)); err != nil {
+		return nil, err
 	}
 
-	file, err := ioutil.ReadFile(filename)
-	if err != nil {
-		panic(err)
+	return &config, nil
+}
+
+// jsonError converts a JSON decoding error to `Errors`.
+func jsonError(filename string, data []byte, err error) error {
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		line, col := position(data, e.Offset)
+		return Errors{{
+			Source: filename,
+			Err:    fmt.Errorf("line %d, column %d: %v", line, col, e),
+		}}
+	default:
+		return Errors{{Source: filename, Err: err}}
 	}
+}
 
-	err = json.Unmarshal(file, &config)
-	if err != nil {
-		panic(err)
+// position returns a line and column of the byte at `offset`.
+func position(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
 	}
 
-	config.ConfigFilePath = filename
+	line, col := 1, 1
+	for _, b := range data[:offset] {
+		if b == '\n' {
+			line++
+			col = 1
+		} else {
+			col++
+		}
+	}
 
-	return &config
+	return line, col
 }
 
 // UpdateFromEnv reads config properties from env variables.
-// It's safer to load sensitive data from env instead of a file.
+// It's safer to load sensitive data from env, or files set by env
+// variables, instead of a config file. It panics on errors; use
+// `LoadEnv()` to handle them.
 func UpdateFromEnv(c interface{}) {
-	cv := reflect.ValueOf(c).Elem()
-
-	for i := 0; i < cv.NumField(); i++ {
-		field := cv.Field(i)
-		jsonTag := cv.Type().Field(i).Tag.Get("json")
-		if jsonTag == "" || jsonTag == "-" {
-			continue
-		}
-
-		loadFromEnv(field, tagName(jsonTag))
+	if err := LoadEnv(c); err != nil {
+		panic(err)
 	}
 }
 
-func tagName(tag string) string {
-	if idx := strings.Index(tag, ","); idx != -1 {
-		return tag[:idx]
+// DefaultEnvSeparator joins names of nested fields in env variable
+// names, for example `DB_HOST` for `Host` in `DB`.
+const DefaultEnvSeparator = "_"
+
+// LoadEnv reads config properties from env variables. Fields of
+// embedded structs without a json tag are read as if they were fields
+// of `c`. Fields of other structs are read from variables prefixed
+// with the struct field name, for example `DB_HOST`. Nil pointers
+// to structs are allocated. Fields with invalid values are left
+// unchanged and all of them are reported as `Errors`.
+//
+// If a variable isn't set, but the variable with `FileEnvSuffix` is,
+// the value is read from that file with trailing newlines removed.
+// It allows using Docker and Kubernetes secrets mounted as files.
+//
+// Only `WithEnvSeparator()` of `opts` is used.
+func LoadEnv(c interface{}, opts ...Option) error {
+	l := loader{envSeparator: DefaultEnvSeparator}
+	for _, opt := range opts {
+		opt(&l)
 	}
-	return tag
+
+	r := l.envReader(Source{Kind: SourceEnv}, os.LookupEnv)
+	r.readStruct(reflect.ValueOf(c).Elem())
+	return r.errs.err()
+}
+
+// envReader reads struct fields from env variables or other sources
+// of variables, like dotenv files.
+type envReader struct {
+	separator  string
+	source     Source
+	lookup     func(key string) (string, bool)
+	provenance Provenance
+	errs       Errors
 }
 
-func loadFromEnv(field reflect.Value, name string) {
-	value, ok := os.LookupEnv(strings.ToUpper(name))
-	if !ok {
-		return
+func (l *loader) envReader(source Source, lookup func(string) (string, bool)) *envReader {
+	return &envReader{
+		separator:  l.envSeparator,
+		source:     source,
+		lookup:     lookup,
+		provenance: l.provenance,
 	}
+}
 
-	switch field.Interface().(type) {
-	case bool:
-		valBool, err := strconv.ParseBool(value)
-		if err != nil {
-			panic(err)
+// readStruct reads fields of `v` visited by `walkFields()`.
+func (r *envReader) readStruct(v reflect.Value) {
+	walkFields(v, r.separator, func(f fieldInfo) {
+		source := r.source
+		source.Key = f.Key
+
+		value, ok := r.lookup(f.Key)
+		secretFile := false
+		if r.source.Kind == SourceEnv {
+			filename, found := r.lookup(f.Key + FileEnvSuffix)
+			if found {
+				secretFile = true
+				var err error
+				if ok {
+					err = fmt.Errorf("%s is set as well", f.Key)
+				} else {
+					value, err = readSecretFile(filename)
+				}
+				source.Key = f.Key + FileEnvSuffix
+				if err != nil {
+					r.errs = append(r.errs, &FieldError{
+						Field:  f.Path,
+						Source: source.errorSource(),
+						Key:    source.Key,
+						Err:    err,
+					})
+					return
+				}
+				ok = true
+			}
 		}
-		field.SetBool(valBool)
-	case int:
-		valInt, err := strconv.ParseInt(value, 0, strconv.IntSize)
-		if err != nil {
-			panic(err)
+		if !ok {
+			return
 		}
-		field.SetInt(valInt)
-	case float64:
-		valFloat, err := strconv.ParseFloat(value, 64)
-		if err != nil {
-			panic(err)
+
+		if err := decodeField(f, value); err != nil {
+			e := fieldError(f, source, value, err)
+			if secretFile {
+				// Contents of secret files are never reported.
+				e.Value = redact(value)
+			}
+			r.errs = append(r.errs, e)
+			return
 		}
-		field.SetFloat(valFloat)
-	case string:
-		field.SetString(value)
-	default:
-		panic(fmt.Sprintf("Invalid field type: %T", field.Interface()))
+		r.provenance.set(f.Path, source)
+	})
+}
+
+func tagName(tag string) string {
+	if idx := strings.Index(tag, ","); idx != -1 {
+		return tag[:idx]
 	}
+	return tag
 }
@@ -3,6 +3,7 @@ package config
 import (
 	"io/ioutil"
 	"os"
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -75,7 +76,7 @@ func TestEmbeddedConfigFromEnvWithUnsupportedType(t *testing.T) {
 	os.Setenv("A", "1")
 	c := &struct {
 		*Config
-		A []byte `json:"a"`
+		A chan int `json:"a"`
 	}{
 		Config: NewConfig(true),
 	}
@@ -83,3 +84,135 @@ func TestEmbeddedConfigFromEnvWithUnsupportedType(t *testing.T) {
 		UpdateFromEnv(c)
 	})
 }
+
+func TestLoadFileErrors(t *testing.T) {
+	_, err := LoadFile("/a/b/c")
+	assert.True(t, os.IsNotExist(err))
+
+	tmpfile, err := ioutil.TempFile("", "test.json")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.Remove(tmpfile.Name())
+
+	ioutil.WriteFile(tmpfile.Name(), []byte("{\n  \"debug\": \"yes\"\n}"), 0644)
+	_, err = LoadFile(tmpfile.Name())
+	if assert.IsType(t, Errors{}, err) {
+		fieldErr := err.(Errors)[0]
+		assert.Equal(t, "debug", fieldErr.Field)
+		assert.Equal(t, tmpfile.Name(), fieldErr.Source)
+		assert.Equal(t, "bool", fieldErr.Type)
+		assert.Contains(t, err.Error(), "line 2, column ")
+	}
+
+	ioutil.WriteFile(tmpfile.Name(), []byte("{\n  \"debug\": true,\n}"), 0644)
+	_, err = LoadFile(tmpfile.Name())
+	if assert.IsType(t, Errors{}, err) {
+		assert.Contains(t, err.Error(), "line 3, column 2")
+	}
+}
+
+func TestLoadEnvErrors(t *testing.T) {
+	os.Setenv("A", "x")
+	os.Setenv("B", "2.5")
+	os.Setenv("C", "abc")
+	defer func() {
+		os.Unsetenv("A")
+		os.Unsetenv("B")
+		os.Unsetenv("C")
+	}()
+
+	c := &struct {
+		A int      `json:"a"`
+		B float64  `json:"b"`
+		C chan int `json:"c"`
+	}{A: 1}
+	err := LoadEnv(c)
+	if assert.IsType(t, Errors{}, err) {
+		errs := err.(Errors)
+		if assert.Len(t, errs, 2) {
+			assert.Equal(t, &FieldError{
+				Field:  "a",
+				Source: "env",
+				Key:    "A",
+				Value:  "x",
+				Type:   "int",
+				Err:    strconv.ErrSyntax,
+			}, errs[0])
+			assert.Equal(t, ErrUnsupportedType, errs[1].Err)
+		}
+		assert.Equal(t, `config: 2 errors:
+  config: a: env A: cannot use "x" as int: invalid syntax
+  config: c: env C: cannot use "abc" as chan int: unsupported field type`, err.Error())
+	}
+	assert.Equal(t, 1, c.A)
+	assert.Equal(t, 2.5, c.B)
+}
+
+func TestNestedConfigFromEnv(t *testing.T) {
+	env := map[string]string{
+		"DEBUG":           "true",
+		"NAME":            "app",
+		"DB_HOST":         "localhost",
+		"DB_PORT":         "5432",
+		"DB_REPLICA_HOST": "replica",
+		"CACHE_TTL":       "60",
+	}
+	for k, v := range env {
+		os.Setenv(k, v)
+	}
+	defer func() {
+		for k := range env {
+			os.Unsetenv(k)
+		}
+	}()
+
+	type replica struct {
+		Host string `json:"host"`
+	}
+	type db struct {
+		Host    string   `json:"host"`
+		Port    int      `json:"port"`
+		Replica *replica `json:"replica"`
+	}
+	c := &struct {
+		*Config
+		Name  string `json:"name"`
+		DB    db     `json:"db"`
+		Cache *struct {
+			TTL int `json:"ttl"`
+		} `json:"cache"`
+	}{}
+
+	if assert.NoError(t, LoadEnv(c)) {
+		if assert.NotNil(t, c.Config) {
+			assert.True(t, c.Debug)
+		}
+		assert.Equal(t, "app", c.Name)
+		assert.Equal(t, "localhost", c.DB.Host)
+		assert.Equal(t, 5432, c.DB.Port)
+		if assert.NotNil(t, c.DB.Replica) {
+			assert.Equal(t, "replica", c.DB.Replica.Host)
+		}
+		if assert.NotNil(t, c.Cache) {
+			assert.Equal(t, 60, c.Cache.TTL)
+		}
+	}
+}
+
+func TestNestedConfigFromEnvWithSeparator(t *testing.T) {
+	os.Setenv("DB__PORT", "x")
+	defer os.Unsetenv("DB__PORT")
+
+	c := &struct {
+		DB struct {
+			Port int `json:"port"`
+		} `json:"db"`
+	}{}
+
+	err := LoadEnv(c, WithEnvSeparator("__"))
+	if assert.IsType(t, Errors{}, err) {
+		assert.Equal(t, "db.port", err.(Errors)[0].Field)
+		assert.Equal(t, "DB__PORT", err.(Errors)[0].Key)
+	}
+}
@@ -0,0 +1,219 @@
+package config
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Default delimiters of slice items and map keys and values. They can
+// be changed with `delim` and `kvdelim` tags, for example:
+//
+//	Hosts []string          `json:"hosts" delim:";"`
+//	Limits map[string]int   `json:"limits" kvdelim:":"`
+const (
+	DefaultDelimiter      = ","
+	DefaultValueDelimiter = "="
+)
+
+type (
+	// Decoder is implemented by types which decode themselves from
+	// a raw env value. It takes precedence over
+	// `encoding.TextUnmarshaler`.
+	Decoder interface {
+		Decode(value string) error
+	}
+
+	// ByteSize is a size in bytes. It's decoded from values like
+	// "512", "512MB" or "1.5GiB". KB, MB, GB and TB are powers of 1000
+	// and KiB, MiB, GiB and TiB are powers of 1024. Units are case
+	// insensitive and the trailing B is optional.
+	ByteSize uint64
+)
+
+var (
+	decoderType         = reflect.TypeOf((*Decoder)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+	urlType             = reflect.TypeOf(url.URL{})
+
+	errInvalidByteSize = errors.New("invalid byte size")
+)
+
+var byteUnits = map[string]float64{
+	"":   1,
+	"k":  1e3,
+	"m":  1e6,
+	"g":  1e9,
+	"t":  1e12,
+	"ki": 1 << 10,
+	"mi": 1 << 20,
+	"gi": 1 << 30,
+	"ti": 1 << 40,
+}
+
+// UnmarshalText implements `encoding.TextUnmarshaler`, so byte sizes
+// can be used in JSON files as well.
+func (s *ByteSize) UnmarshalText(text []byte) error {
+	value := strings.TrimSpace(string(text))
+	i := strings.IndexFunc(value, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != '.'
+	})
+	if i == -1 {
+		i = len(value)
+	}
+
+	unit := strings.TrimSpace(strings.ToLower(value[i:]))
+	unit = strings.TrimSuffix(unit, "b")
+	multiplier, ok := byteUnits[unit]
+	if !ok || i == 0 {
+		return errInvalidByteSize
+	}
+
+	n, err := strconv.ParseFloat(value[:i], 64)
+	if err != nil {
+		return errInvalidByteSize
+	}
+
+	*s = ByteSize(n * multiplier)
+	return nil
+}
+
+// isDecodable returns true for types decoded from a single value.
+func isDecodable(t reflect.Type) bool {
+	ptr := reflect.PtrTo(t)
+	return t == urlType ||
+		ptr.Implements(decoderType) ||
+		ptr.Implements(textUnmarshalerType)
+}
+
+// decodeValue sets `v` to the decoded `value`. `delim` and `kvdelim`
+// split slices and maps.
+func decodeValue(v reflect.Value, value, delim, kvdelim string) error {
+	if v.CanAddr() {
+		switch u := v.Addr().Interface().(type) {
+		case Decoder:
+			return u.Decode(value)
+		case encoding.TextUnmarshaler:
+			return u.UnmarshalText([]byte(value))
+		}
+	}
+
+	switch v.Type() {
+	case durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	case urlType:
+		u, err := url.Parse(value)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(*u))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(v.Type().Elem())
+		if err := decodeValue(elem.Elem(), value, delim, kvdelim); err != nil {
+			return err
+		}
+		v.Set(elem)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(value, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			v.SetBytes([]byte(value))
+			return nil
+		}
+
+		items := splitValue(value, delim)
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := decodeValue(slice.Index(i), item, delim, kvdelim); err != nil {
+				return fmt.Errorf("item %d: %v", i, unwrapNumError(err))
+			}
+		}
+		v.Set(slice)
+	case reflect.Map:
+		m := reflect.MakeMap(v.Type())
+		for _, item := range splitValue(value, delim) {
+			parts := strings.SplitN(item, kvdelim, 2)
+			if len(parts) != 2 {
+				return fmt.Errorf("item %q: missing %q", item, kvdelim)
+			}
+
+			key := reflect.New(v.Type().Key()).Elem()
+			if err := decodeValue(key, strings.TrimSpace(parts[0]), delim, kvdelim); err != nil {
+				return fmt.Errorf("key %q: %v", parts[0], unwrapNumError(err))
+			}
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := decodeValue(elem, strings.TrimSpace(parts[1]), delim, kvdelim); err != nil {
+				return fmt.Errorf("key %q: %v", parts[0], unwrapNumError(err))
+			}
+			m.SetMapIndex(key, elem)
+		}
+		v.Set(m)
+	default:
+		return ErrUnsupportedType
+	}
+
+	return nil
+}
+
+// splitValue splits a list and trims spaces around items.
+// An empty value is an empty list.
+func splitValue(value, delim string) []string {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+
+	items := strings.Split(value, delim)
+	for i := range items {
+		items[i] = strings.TrimSpace(items[i])
+	}
+	return items
+}
+
+// unwrapNumError removes the function name and the value from strconv
+// errors, as the value is reported separately.
+func unwrapNumError(err error) error {
+	if numErr, ok := err.(*strconv.NumError); ok {
+		return numErr.Err
+	}
+	return err
+}
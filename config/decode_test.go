@@ -0,0 +1,151 @@
+package config
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type upperString string
+
+func (s *upperString) Decode(value string) error {
+	if value == "" {
+		return errors.New("empty value")
+	}
+	*s = upperString(strings.ToUpper(value))
+	return nil
+}
+
+func TestByteSize(t *testing.T) {
+	for value, expected := range map[string]ByteSize{
+		"512":    512,
+		"512B":   512,
+		"2kb":    2000,
+		"512MB":  512e6,
+		"1.5GiB": 1536 << 20,
+		"1 Ki":   1024,
+	} {
+		var s ByteSize
+		if assert.NoError(t, s.UnmarshalText([]byte(value)), value) {
+			assert.Equal(t, expected, s, value)
+		}
+	}
+
+	for _, value := range []string{"", "MB", "12XB", "1.2.3MB"} {
+		var s ByteSize
+		assert.Error(t, s.UnmarshalText([]byte(value)), value)
+	}
+}
+
+func TestRichTypesFromEnv(t *testing.T) {
+	env := map[string]string{
+		"INT8":     "-8",
+		"UINT16":   "0x10",
+		"FLOAT32":  "1.5",
+		"TIMEOUT":  "1m30s",
+		"STARTED":  "2017-05-01T10:00:00Z",
+		"ENDPOINT": "https://example.com/api",
+		"IP":       "10.0.0.1",
+		"MEMORY":   "512MB",
+		"PORTS":    "80, 443",
+		"HOSTS":    "a;b",
+		"LIMITS":   "cpu=2,memory=4",
+		"WEIGHTS":  "a:0.5",
+		"RETRIES":  "3",
+		"NAME":     "app",
+		"TOKEN":    "secret",
+	}
+	for k, v := range env {
+		os.Setenv(k, v)
+	}
+	defer func() {
+		for k := range env {
+			os.Unsetenv(k)
+		}
+	}()
+
+	c := &struct {
+		Int8     int8               `json:"int8"`
+		Uint16   uint16             `json:"uint16"`
+		Float32  float32            `json:"float32"`
+		Timeout  time.Duration      `json:"timeout"`
+		Started  time.Time          `json:"started"`
+		Endpoint *url.URL           `json:"endpoint"`
+		IP       net.IP             `json:"ip"`
+		Memory   ByteSize           `json:"memory"`
+		Ports    []int              `json:"ports"`
+		Hosts    []string           `json:"hosts" delim:";"`
+		Limits   map[string]int     `json:"limits"`
+		Weights  map[string]float64 `json:"weights" kvdelim:":"`
+		Retries  *int               `json:"retries"`
+		Name     upperString        `json:"name"`
+		Token    []byte             `json:"token"`
+	}{}
+
+	if !assert.NoError(t, LoadEnv(c)) {
+		return
+	}
+	assert.Equal(t, int8(-8), c.Int8)
+	assert.Equal(t, uint16(16), c.Uint16)
+	assert.Equal(t, float32(1.5), c.Float32)
+	assert.Equal(t, 90*time.Second, c.Timeout)
+	assert.Equal(t, time.Date(2017, 5, 1, 10, 0, 0, 0, time.UTC), c.Started.UTC())
+	if assert.NotNil(t, c.Endpoint) {
+		assert.Equal(t, "example.com", c.Endpoint.Host)
+	}
+	assert.Equal(t, "10.0.0.1", c.IP.String())
+	assert.Equal(t, ByteSize(512e6), c.Memory)
+	assert.Equal(t, []int{80, 443}, c.Ports)
+	assert.Equal(t, []string{"a", "b"}, c.Hosts)
+	assert.Equal(t, map[string]int{"cpu": 2, "memory": 4}, c.Limits)
+	assert.Equal(t, map[string]float64{"a": 0.5}, c.Weights)
+	if assert.NotNil(t, c.Retries) {
+		assert.Equal(t, 3, *c.Retries)
+	}
+	assert.Equal(t, upperString("APP"), c.Name)
+	assert.Equal(t, []byte("secret"), c.Token)
+}
+
+func TestRichTypesFromEnvErrors(t *testing.T) {
+	env := map[string]string{
+		"INT8":   "300",
+		"PORTS":  "80,http",
+		"LIMITS": "cpu",
+		"NAME":   "",
+	}
+	for k, v := range env {
+		os.Setenv(k, v)
+	}
+	defer func() {
+		for k := range env {
+			os.Unsetenv(k)
+		}
+	}()
+
+	c := &struct {
+		Int8   int8           `json:"int8"`
+		Ports  []int          `json:"ports"`
+		Limits map[string]int `json:"limits"`
+		Name   upperString    `json:"name"`
+	}{}
+
+	err := LoadEnv(c)
+	if assert.IsType(t, Errors{}, err) {
+		var messages []string
+		for _, fieldErr := range err.(Errors) {
+			messages = append(messages, fieldErr.Err.Error())
+		}
+		assert.Equal(t, []string{
+			"value out of range",
+			"item 1: invalid syntax",
+			`item "cpu": missing "="`,
+			"empty value",
+		}, messages)
+	}
+}
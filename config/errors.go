@@ -0,0 +1,87 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnsupportedType is returned for fields which can't be loaded
+// from env variables.
+var ErrUnsupportedType = errors.New("unsupported field type")
+
+type (
+	// FieldError describes a config value which couldn't be loaded
+	// or is invalid.
+	FieldError struct {
+		// Field is a path of the field, for example "debug".
+		// It's empty if the error isn't related to a field.
+		Field string
+
+		// Source is where the value comes from, for example "env"
+		// or a file name.
+		Source string
+
+		// Key is a name of the value in the source, for example
+		// an env variable name.
+		Key string
+
+		// Value is the raw value.
+		Value string
+
+		// Type is the expected type, for example "int".
+		Type string
+
+		Err error
+	}
+
+	// Errors contains every problem found while loading
+	// and validating a config.
+	Errors []*FieldError
+)
+
+func (e *FieldError) Error() string {
+	var buf bytes.Buffer
+	buf.WriteString("config: ")
+	if e.Field != "" {
+		buf.WriteString(e.Field + ": ")
+	}
+
+	if source := strings.TrimSpace(e.Source + " " + e.Key); source != "" {
+		buf.WriteString(source + ": ")
+	}
+
+	switch {
+	case e.Type != "" && e.Value != "":
+		fmt.Fprintf(&buf, "cannot use %q as %s: ", e.Value, e.Type)
+	case e.Type != "":
+		fmt.Fprintf(&buf, "expected %s: ", e.Type)
+	case e.Value != "":
+		fmt.Fprintf(&buf, "value %q: ", e.Value)
+	}
+	buf.WriteString(e.Err.Error())
+
+	return buf.String()
+}
+
+func (e Errors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "config: %d errors:", len(e))
+	for _, err := range e {
+		buf.WriteString("\n  " + err.Error())
+	}
+	return buf.String()
+}
+
+// err returns the list as an error or nil if it's empty.
+func (e Errors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// fieldInfo is a field visited by `walkFields()`.
+type fieldInfo struct {
+	Value reflect.Value
+	Field reflect.StructField
+
+	// Path is a path of json names, for example "db.host".
+	Path string
+
+	// Key is an env variable name, for example "DB_HOST".
+	Key string
+}
+
+// walkFields calls `fn` for every field of `v` which is set from a single
+// value. Fields of embedded structs without a json tag are visited as if
+// they were fields of `v`. Fields of other structs get the struct name
+// prefix joined with `separator` in keys. Nil pointers to structs are
+// allocated. Fields without a json tag, ignored with "-" or unexported
+// are skipped.
+func walkFields(v reflect.Value, separator string, fn func(fieldInfo)) {
+	walkStruct(v, "", "", separator, fn)
+}
+
+func walkStruct(cv reflect.Value, path, prefix, separator string, fn func(fieldInfo)) {
+	for i := 0; i < cv.NumField(); i++ {
+		field := cv.Field(i)
+		structField := cv.Type().Field(i)
+		jsonTag := structField.Tag.Get("json")
+
+		if jsonTag == "-" || !field.CanSet() {
+			continue
+		}
+
+		nested := isNestedStruct(field.Type())
+		if nested {
+			if field.Kind() == reflect.Ptr {
+				if field.IsNil() {
+					field.Set(reflect.New(field.Type().Elem()))
+				}
+				field = field.Elem()
+			}
+
+			if structField.Anonymous && jsonTag == "" {
+				walkStruct(field, path, prefix, separator, fn)
+				continue
+			}
+		}
+
+		name := tagName(jsonTag)
+		if name == "" {
+			if structField.Anonymous || jsonTag == "" {
+				continue
+			}
+			name = structField.Name
+		}
+
+		fieldPath, key := name, strings.ToUpper(name)
+		if path != "" {
+			fieldPath = path + "." + name
+			key = prefix + separator + key
+		}
+
+		if nested {
+			walkStruct(field, fieldPath, key, separator, fn)
+			continue
+		}
+
+		fn(fieldInfo{Value: field, Field: structField, Path: fieldPath, Key: key})
+	}
+}
+
+// isNestedStruct returns true for structs and pointers to structs
+// whose fields are read separately. Structs decoded from a single
+// value, like `time.Time`, are not nested.
+func isNestedStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && !isDecodable(t)
+}
+
+// decodeField sets the field to the decoded `value`.
+func decodeField(f fieldInfo, value string) error {
+	delim, kvdelim := f.delims()
+	return unwrapNumError(decodeValue(f.Value, value, delim, kvdelim))
+}
+
+// delims returns delimiters of slices and maps set by `delim` and
+// `kvdelim` tags or the defaults.
+func (f fieldInfo) delims() (string, string) {
+	delim, kvdelim := f.Field.Tag.Get("delim"), f.Field.Tag.Get("kvdelim")
+	if delim == "" {
+		delim = DefaultDelimiter
+	}
+	if kvdelim == "" {
+		kvdelim = DefaultValueDelimiter
+	}
+	return delim, kvdelim
+}
+
+// fieldError returns an error of a value which couldn't be decoded.
+func fieldError(f fieldInfo, source Source, value string, err error) *FieldError {
+	return &FieldError{
+		Field:  f.Path,
+		Source: source.errorSource(),
+		Key:    source.Key,
+		Value:  safeValue(f, value),
+		Type:   f.Value.Type().String(),
+		Err:    err,
+	}
+}
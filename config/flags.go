@@ -0,0 +1,198 @@
+package config
+
+import (
+	"encoding"
+	"flag"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// flagValue keeps a raw flag value which is decoded by `Load()`, so
+// flags override files and env variables no matter when they're parsed.
+type flagValue struct {
+	field  fieldInfo
+	value  string
+	isBool bool
+	repeat string
+	set    bool
+}
+
+// BindFlags defines a flag in `fs` for every field of `target` which
+// `Load()` reads. Flag names are paths of json names with dashes, like
+// "db-host", and can be changed with a `flag` tag. Fields tagged
+// `flag:"-"` are skipped. Usage is taken from a `desc` tag and defaults
+// from current values of `target` or `default` tags, except for secret
+// fields:
+//
+//	Host string `json:"host" flag:"db" desc:"database host"`
+//
+// Values are checked when flags are parsed, but fields are set
+// by `Load()` with `WithFlags(fs)`. Flags of slices and maps can be
+// repeated to add items. It returns an error if a flag is already
+// defined in `fs`.
+func BindFlags(fs *flag.FlagSet, target interface{}) error {
+	v, err := structValue(target)
+	if err != nil {
+		return err
+	}
+
+	walkFields(v, DefaultEnvSeparator, func(f fieldInfo) {
+		name := flagName(f)
+		if name == "" || err != nil {
+			return
+		}
+		if fs.Lookup(name) != nil {
+			err = fmt.Errorf("config: flag %q of field %s is already defined", name, f.Path)
+			return
+		}
+
+		value := &flagValue{field: f, value: formatField(f)}
+		if d, ok := f.Field.Tag.Lookup("default"); ok && isZero(f.Value) {
+			value.value = d
+		}
+		if isSecret(f) {
+			value.value = ""
+		}
+
+		t := f.Value.Type()
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		switch {
+		case t.Kind() == reflect.Bool:
+			value.isBool = true
+		case t.Kind() == reflect.Map,
+			t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8:
+			value.repeat, _ = f.delims()
+		}
+
+		fs.Var(value, name, f.Field.Tag.Get("desc"))
+	})
+
+	return err
+}
+
+// flagName returns a name of the field flag or an empty string
+// if the field has no flag.
+func flagName(f fieldInfo) string {
+	name := f.Field.Tag.Get("flag")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		name = strings.Replace(f.Path, ".", "-", -1)
+	}
+	return name
+}
+
+func (v *flagValue) String() string {
+	if v == nil {
+		return ""
+	}
+	return v.value
+}
+
+// Set checks if `s` can be decoded and keeps it. Repeated values
+// of slices and maps are joined.
+func (v *flagValue) Set(s string) error {
+	if v.set && v.repeat != "" {
+		s = v.value + v.repeat + s
+	}
+
+	check := v.field
+	check.Value = reflect.New(check.Value.Type()).Elem()
+	if err := decodeField(check, s); err != nil {
+		return err
+	}
+
+	v.value, v.set = s, true
+	return nil
+}
+
+// IsBoolFlag allows passing bool flags without a value.
+func (v *flagValue) IsBoolFlag() bool {
+	return v.isBool
+}
+
+// loadFlags sets fields from flags set on the command line.
+func (l *loader) loadFlags(v reflect.Value) Errors {
+	set := make(map[string]*flag.Flag)
+	l.flags.Visit(func(f *flag.Flag) {
+		set[f.Name] = f
+	})
+
+	var errs Errors
+	walkFields(v, l.envSeparator, func(f fieldInfo) {
+		name := flagName(f)
+		if name == "" {
+			return
+		}
+		fl, ok := set[name]
+		if !ok {
+			fl, ok = set[f.Path]
+		}
+		if !ok {
+			return
+		}
+
+		value := fl.Value.String()
+		source := Source{Kind: SourceFlag, Key: fl.Name}
+		if err := decodeField(f, value); err != nil {
+			errs = append(errs, fieldError(f, source, value, err))
+			return
+		}
+		l.provenance.set(f.Path, source)
+	})
+	return errs
+}
+
+// formatField formats the field value, so it can be decoded back.
+func formatField(f fieldInfo) string {
+	delim, kvdelim := f.delims()
+	return formatValue(f.Value, delim, kvdelim)
+}
+
+func formatValue(v reflect.Value, delim, kvdelim string) string {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	if v.CanAddr() {
+		switch m := v.Addr().Interface().(type) {
+		case encoding.TextMarshaler:
+			if text, err := m.MarshalText(); err == nil {
+				return string(text)
+			}
+		case fmt.Stringer:
+			return m.String()
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes())
+		}
+
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = formatValue(v.Index(i), delim, kvdelim)
+		}
+		return strings.Join(items, delim)
+	case reflect.Map:
+		items := make([]string, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			items = append(items, formatValue(key, delim, kvdelim)+kvdelim+
+				formatValue(v.MapIndex(key), delim, kvdelim))
+		}
+		sort.Strings(items)
+		return strings.Join(items, delim)
+	}
+
+	return fmt.Sprint(v.Interface())
+}
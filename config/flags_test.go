@@ -0,0 +1,90 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type flagConfig struct {
+	*Config
+	Name    string            `json:"name" desc:"app name"`
+	Port    int               `json:"port" flag:"listen" default:"8080"`
+	Timeout time.Duration     `json:"timeout"`
+	Limit   ByteSize          `json:"limit"`
+	Hosts   []string          `json:"hosts"`
+	Labels  map[string]string `json:"labels" delim:";"`
+	Secret  string            `json:"secret" flag:"-"`
+	DB      struct {
+		Host string `json:"host"`
+	} `json:"db"`
+}
+
+func TestBindFlags(t *testing.T) {
+	c := &flagConfig{Name: "app", Timeout: time.Second, Hosts: []string{"a", "b"}}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	if !assert.NoError(t, BindFlags(fs, c)) {
+		return
+	}
+
+	assert.Nil(t, fs.Lookup("secret"))
+	assert.Nil(t, fs.Lookup("config_filepath"))
+	if f := fs.Lookup("name"); assert.NotNil(t, f) {
+		assert.Equal(t, "app", f.DefValue)
+		assert.Equal(t, "app name", f.Usage)
+	}
+	if f := fs.Lookup("listen"); assert.NotNil(t, f) {
+		assert.Equal(t, "8080", f.DefValue)
+	}
+	assert.Equal(t, "1s", fs.Lookup("timeout").DefValue)
+	assert.Equal(t, "a,b", fs.Lookup("hosts").DefValue)
+	assert.NotNil(t, fs.Lookup("db-host"))
+	assert.NotNil(t, fs.Lookup("debug"))
+
+	err := fs.Parse([]string{
+		"-debug", "-db-host", "db", "-limit", "1MiB",
+		"-hosts", "c", "-hosts", "d", "-labels", "a=1;b=2",
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "app", c.Name, "fields are set by Load")
+
+	os.Setenv("DB_HOST", "from-env")
+	defer os.Unsetenv("DB_HOST")
+	os.Setenv("NAME", "from-env")
+	defer os.Unsetenv("NAME")
+
+	p := Provenance{}
+	err = Load(c, WithFlags(fs), WithProvenance(p))
+	if assert.NoError(t, err) {
+		assert.True(t, c.Debug)
+		assert.Equal(t, "from-env", c.Name)
+		assert.Equal(t, 8080, c.Port)
+		assert.Equal(t, "db", c.DB.Host)
+		assert.Equal(t, ByteSize(1<<20), c.Limit)
+		assert.Equal(t, []string{"c", "d"}, c.Hosts)
+		assert.Equal(t, map[string]string{"a": "1", "b": "2"}, c.Labels)
+		assert.Equal(t, Source{Kind: SourceFlag, Key: "db-host"}, p["db.host"])
+	}
+}
+
+func TestBindFlagsErrors(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(&bytes.Buffer{})
+	fs.String("name", "", "")
+	err := BindFlags(fs, &flagConfig{})
+	assert.EqualError(t, err, `config: flag "name" of field name is already defined`)
+
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(&bytes.Buffer{})
+	assert.NoError(t, BindFlags(fs, &flagConfig{}))
+	err = fs.Parse([]string{"-listen", "http"})
+	assert.Contains(t, err.Error(), `invalid value "http" for flag -listen`)
+
+	assert.Error(t, BindFlags(fs, flagConfig{}))
+}
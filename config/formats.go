@@ -0,0 +1,376 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/adambabik/go-collections/internal/yamlutil"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Formats of config files.
+const (
+	FormatJSON   Format = "json"
+	FormatYAML   Format = "yaml"
+	FormatTOML   Format = "toml"
+	FormatDotenv Format = "dotenv"
+)
+
+// Format is a config file format.
+type Format string
+
+// detectFormat returns a format by a file extension. Files named
+// `.env` or `.env.*` are dotenv files. JSON is used for unknown
+// extensions.
+func detectFormat(filename string) Format {
+	base := filepath.Base(filename)
+	if base == ".env" || strings.HasPrefix(base, ".env.") {
+		return FormatDotenv
+	}
+
+	switch strings.ToLower(filepath.Ext(base)) {
+	case ".yml", ".yaml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	case ".env":
+		return FormatDotenv
+	default:
+		return FormatJSON
+	}
+}
+
+// loadFile sets fields of `v` to values of a file. Values of JSON,
+// YAML and TOML files are decoded field by field like env variables,
+// so durations, byte sizes and other types work the same in every
+// source, and errors point at lines and columns of values.
+func (l *loader) loadFile(v reflect.Value, f file, data []byte) Errors {
+	source := Source{Kind: SourceFile, Name: f.name}
+
+	var errs Errors
+	if f.format == FormatDotenv {
+		vars, parseErrs := parseDotenv(f.name, data)
+		if len(parseErrs) > 0 {
+			return parseErrs
+		}
+
+		r := l.envReader(source, func(key string) (string, bool) {
+			value, ok := vars[key]
+			return value, ok
+		})
+		r.readStruct(v)
+		errs = r.errs
+	} else {
+		values, parseErrs := decodeFile(f.name, data, f.format)
+		if len(parseErrs) > 0 {
+			return parseErrs
+		}
+
+		var positions map[string]textPos
+		walkFields(v, l.envSeparator, func(field fieldInfo) {
+			raw, ok := lookupPath(values, field.Path)
+			if !ok || raw == nil {
+				return
+			}
+
+			delim, kvdelim := field.delims()
+			if err := decodeFileValue(field.Value, raw, delim, kvdelim); err != nil {
+				if positions == nil {
+					positions = filePositions(data, f.format)
+				}
+				errs = append(errs, fileValueError(field, source, raw, err, positions))
+				return
+			}
+			l.provenance.set(field.Path, source)
+		})
+	}
+
+	if c := embeddedConfig(v); c != nil {
+		c.ConfigFilePath = f.name
+	}
+	return errs
+}
+
+// decodeFile decodes JSON, YAML or TOML data into an object like
+// `encoding/json` does for `interface{}`. JSON numbers are kept
+// as `json.Number`, so they aren't rounded.
+func decodeFile(filename string, data []byte, format Format) (map[string]interface{}, Errors) {
+	var (
+		v   interface{}
+		err error
+	)
+	switch format {
+	case FormatJSON:
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, jsonError(filename, data, err).(Errors)
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		err = dec.Decode(&v)
+	case FormatYAML:
+		err = yaml.Unmarshal(data, &v)
+		if err != nil {
+			err = yamlError(data, err)
+		} else {
+			v, err = yamlutil.Convert(v)
+		}
+	case FormatTOML:
+		var m map[string]interface{}
+		_, err = toml.Decode(string(data), &m)
+		v = m
+	default:
+		err = fmt.Errorf("unknown format %q", format)
+	}
+	if err != nil {
+		return nil, Errors{{Source: filename, Err: err}}
+	}
+
+	if v == nil {
+		return nil, nil
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, Errors{{Source: filename, Err: fmt.Errorf("expected an object, got %s", valueKind(v))}}
+	}
+	return m, nil
+}
+
+// decodeFileValue sets `v` to a value decoded from a file. Scalars are
+// decoded like env values. Lists and objects set slices and maps item
+// by item. Other values, like lists of structs, are decoded with
+// `encoding/json`.
+func decodeFileValue(v reflect.Value, raw interface{}, delim, kvdelim string) error {
+	if v.Kind() == reflect.Interface {
+		v.Set(reflect.ValueOf(raw))
+		return nil
+	}
+	if value, ok := scalarString(raw); ok {
+		return decodeValue(v, value, delim, kvdelim)
+	}
+
+	switch {
+	case isDecodable(v.Type()):
+	case v.Kind() == reflect.Ptr:
+		elem := reflect.New(v.Type().Elem())
+		if err := decodeFileValue(elem.Elem(), raw, delim, kvdelim); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	case v.Kind() == reflect.Slice:
+		items, ok := raw.([]interface{})
+		if tables, isTables := raw.([]map[string]interface{}); isTables {
+			// Arrays of TOML tables.
+			for _, table := range tables {
+				items = append(items, table)
+			}
+			ok = true
+		}
+		if !ok {
+			break
+		}
+
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := decodeFileValue(slice.Index(i), item, delim, kvdelim); err != nil {
+				return fmt.Errorf("item %d: %v", i, unwrapNumError(err))
+			}
+		}
+		v.Set(slice)
+		return nil
+	case v.Kind() == reflect.Map:
+		items, ok := raw.(map[string]interface{})
+		if !ok {
+			break
+		}
+
+		keys := make([]string, 0, len(items))
+		for k := range items {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		m := reflect.MakeMap(v.Type())
+		for _, k := range keys {
+			key := reflect.New(v.Type().Key()).Elem()
+			if err := decodeValue(key, k, delim, kvdelim); err != nil {
+				return fmt.Errorf("key %q: %v", k, unwrapNumError(err))
+			}
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := decodeFileValue(elem, items[k], delim, kvdelim); err != nil {
+				return fmt.Errorf("key %q: %v", k, unwrapNumError(err))
+			}
+			m.SetMapIndex(key, elem)
+		}
+		v.Set(m)
+		return nil
+	case v.Kind() == reflect.Struct:
+		data, err := json.Marshal(raw)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(data, v.Addr().Interface())
+	}
+
+	return fmt.Errorf("got %s", valueKind(raw))
+}
+
+// scalarString formats a decoded scalar like an env value.
+func scalarString(raw interface{}) (string, bool) {
+	switch v := raw.(type) {
+	case string:
+		return v, true
+	case bool:
+		return strconv.FormatBool(v), true
+	case json.Number:
+		return v.String(), true
+	case int:
+		return strconv.Itoa(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case uint64:
+		return strconv.FormatUint(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), true
+	case time.Time:
+		return v.Format(time.RFC3339Nano), true
+	}
+	return "", false
+}
+
+// valueKind describes a decoded value in errors.
+func valueKind(raw interface{}) string {
+	switch raw.(type) {
+	case []interface{}, []map[string]interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", raw)
+}
+
+// fileValueError returns an error of a file value which couldn't be
+// decoded, with a position of the value if it's found.
+func fileValueError(f fieldInfo, source Source, raw interface{}, err error, positions map[string]textPos) *FieldError {
+	value, _ := scalarString(raw)
+	e := fieldError(f, source, value, unwrapNumError(err))
+	if pos, ok := lookupPosition(positions, f.Path); ok {
+		e.Err = fmt.Errorf("line %d, column %d: %v", pos.line, pos.col, e.Err)
+	}
+	return e
+}
+
+// parseDotenv parses lines in format KEY=VALUE. Lines can start with
+// `export`. Values can be single-quoted, taken literally, or
+// double-quoted, with \n, \t, \" and \\ escapes. `#` starts a comment
+// at the beginning of a line or after a space in unquoted values.
+func parseDotenv(filename string, data []byte) (map[string]string, Errors) {
+	vars := make(map[string]string)
+	var errs Errors
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		key, value, col, err := parseDotenvLine(scanner.Text())
+		if err != nil {
+			errs = append(errs, &FieldError{
+				Source: filename,
+				Err:    fmt.Errorf("line %d, column %d: %v", n, col, err),
+			})
+			continue
+		}
+		if key != "" {
+			vars[key] = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, &FieldError{Source: filename, Err: err})
+	}
+
+	return vars, errs
+}
+
+// parseDotenvLine returns an empty key for empty lines and comments.
+// On errors, it returns a column of the problem.
+func parseDotenvLine(line string) (string, string, int, error) {
+	rest := strings.TrimLeft(line, " \t")
+	if rest == "" || rest[0] == '#' {
+		return "", "", 0, nil
+	}
+	if strings.HasPrefix(rest, "export ") {
+		rest = strings.TrimLeft(rest[len("export "):], " \t")
+	}
+	col := func(s string) int {
+		return len(line) - len(s) + 1
+	}
+
+	eq := strings.Index(rest, "=")
+	if eq == -1 {
+		return "", "", col(rest), fmt.Errorf("expected KEY=VALUE")
+	}
+	key := strings.TrimSpace(rest[:eq])
+	for _, c := range key {
+		if !(c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' || c == '.') {
+			return "", "", col(rest), fmt.Errorf("invalid key %q", key)
+		}
+	}
+	if key == "" {
+		return "", "", col(rest), fmt.Errorf("missing key")
+	}
+
+	rest = strings.TrimLeft(rest[eq+1:], " \t")
+	if rest == "" {
+		return key, "", 0, nil
+	}
+
+	var value string
+	switch rest[0] {
+	case '\'':
+		end := strings.Index(rest[1:], "'")
+		if end == -1 {
+			return "", "", col(rest), fmt.Errorf("unterminated quoted value")
+		}
+		value, rest = rest[1:end+1], rest[end+2:]
+	case '"':
+		var buf bytes.Buffer
+		i := 1
+		for ; i < len(rest) && rest[i] != '"'; i++ {
+			if rest[i] != '\\' || i+1 == len(rest) {
+				buf.WriteByte(rest[i])
+				continue
+			}
+			i++
+			switch rest[i] {
+			case 'n':
+				buf.WriteByte('\n')
+			case 't':
+				buf.WriteByte('\t')
+			default:
+				buf.WriteByte(rest[i])
+			}
+		}
+		if i >= len(rest) {
+			return "", "", col(rest), fmt.Errorf("unterminated quoted value")
+		}
+		value, rest = buf.String(), rest[i+1:]
+	default:
+		if i := strings.Index(rest, " #"); i != -1 {
+			rest = rest[:i]
+		}
+		return key, strings.TrimSpace(rest), 0, nil
+	}
+
+	if trimmed := strings.TrimLeft(rest, " \t"); trimmed != "" && trimmed[0] != '#' {
+		return "", "", col(trimmed), fmt.Errorf("unexpected text after quoted value")
+	}
+
+	return key, value, 0, nil
+}
@@ -0,0 +1,213 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	dir := filepath.Join(os.TempDir(), "config-formats")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	filename := filepath.Join(dir, name)
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestDetectFormat(t *testing.T) {
+	for filename, format := range map[string]Format{
+		"config.json":      FormatJSON,
+		"config.YML":       FormatYAML,
+		"/etc/app.yaml":    FormatYAML,
+		"config.toml":      FormatTOML,
+		".env":             FormatDotenv,
+		"dir/.env.local":   FormatDotenv,
+		"app.env":          FormatDotenv,
+		"config.json12345": FormatJSON,
+	} {
+		assert.Equal(t, format, detectFormat(filename), filename)
+	}
+}
+
+func TestLoadFormats(t *testing.T) {
+	type config struct {
+		*Config
+		Name string `json:"name"`
+		DB   struct {
+			Host string `json:"host"`
+			Port int    `json:"port"`
+		} `json:"db"`
+	}
+
+	for name, content := range map[string]string{
+		"config.json": `{"debug": true, "name": "app", "db": {"host": "db", "port": 5432}}`,
+		"config.yml":  "debug: true\nname: app\ndb:\n  host: db\n  port: 5432\n",
+		"config.toml": "debug = true\nname = \"app\"\n\n[db]\nhost = \"db\"\nport = 5432\n",
+		".env":        "# local\nexport DEBUG=true\nNAME='app'\nDB_HOST=\"db\" # comment\nDB_PORT=5432\n",
+	} {
+		filename := writeConfigFile(t, name, content)
+		defer os.Remove(filename)
+
+		c := &config{}
+		if assert.NoError(t, Load(c, WithFile(filename), WithEnv(false)), name) {
+			assert.True(t, c.Debug, name)
+			assert.Equal(t, "app", c.Name, name)
+			assert.Equal(t, "db", c.DB.Host, name)
+			assert.Equal(t, 5432, c.DB.Port, name)
+			assert.Equal(t, filename, c.ConfigFilePath, name)
+		}
+	}
+}
+
+func TestLoadExplicitFormat(t *testing.T) {
+	filename := writeConfigFile(t, "config", "debug: true\n")
+	defer os.Remove(filename)
+
+	c := &Config{}
+	if assert.NoError(t, Load(c, WithFileFormat(filename, FormatYAML), WithEnv(false))) {
+		assert.True(t, c.Debug)
+	}
+}
+
+func TestLoadFormatErrors(t *testing.T) {
+	for name, expected := range map[string]string{
+		"bad.yml":  "line 2, column 3: ",
+		"bad.toml": "line 2",
+		".env.bad": "line 2, column 8: unterminated quoted value",
+		"type.yml": `config: debug: ` + filepath.Join(os.TempDir(), "config-formats", "type.yml") +
+			`: cannot use "maybe" as bool: line 1, column 8: invalid syntax`,
+	} {
+		content := map[string]string{
+			"bad.yml":  "debug: true\n  name: [app\n",
+			"bad.toml": "debug = true\nname = \n",
+			".env.bad": "DEBUG=1\nNAME = \"app\n",
+			"type.yml": "debug: maybe\n",
+		}[name]
+		filename := writeConfigFile(t, name, content)
+		defer os.Remove(filename)
+
+		err := Load(&Config{}, WithFile(filename), WithEnv(false))
+		if assert.IsType(t, Errors{}, err, name) {
+			assert.Contains(t, err.Error(), expected, name)
+		}
+	}
+}
+
+func TestLoadFileTypes(t *testing.T) {
+	type config struct {
+		Timeout time.Duration  `json:"timeout"`
+		Memory  ByteSize       `json:"memory"`
+		Started time.Time      `json:"started"`
+		Ports   []int          `json:"ports"`
+		Limits  map[string]int `json:"limits"`
+		Users   []struct {
+			Name string `json:"name"`
+		} `json:"users"`
+	}
+
+	for name, content := range map[string]string{
+		"types.json": `{"timeout": "1m30s", "memory": "1.5GiB", "started": "2017-05-01T10:00:00Z",
+			"ports": [80, "443"], "limits": {"cpu": 2}, "users": [{"name": "admin"}]}`,
+		"types.yml": "timeout: 1m30s\nmemory: 1.5GiB\nstarted: 2017-05-01T10:00:00Z\n" +
+			"ports: [80, 443]\nlimits:\n  cpu: 2\nusers:\n  - name: admin\n",
+		"types.toml": "timeout = \"1m30s\"\nmemory = \"1.5GiB\"\nstarted = 2017-05-01T10:00:00Z\n" +
+			"ports = [80, 443]\n\n[limits]\ncpu = 2\n\n[[users]]\nname = \"admin\"\n",
+	} {
+		filename := writeConfigFile(t, name, content)
+		defer os.Remove(filename)
+
+		c := &config{}
+		if assert.NoError(t, Load(c, WithFile(filename), WithEnv(false)), name) {
+			assert.Equal(t, 90*time.Second, c.Timeout, name)
+			assert.Equal(t, ByteSize(1.5*(1<<30)), c.Memory, name)
+			assert.Equal(t, time.Date(2017, 5, 1, 10, 0, 0, 0, time.UTC), c.Started.UTC(), name)
+			assert.Equal(t, []int{80, 443}, c.Ports, name)
+			assert.Equal(t, map[string]int{"cpu": 2}, c.Limits, name)
+			if assert.Len(t, c.Users, 1, name) {
+				assert.Equal(t, "admin", c.Users[0].Name, name)
+			}
+		}
+	}
+}
+
+func TestLoadFileValueErrors(t *testing.T) {
+	type config struct {
+		Name string `json:"name"`
+		DB   struct {
+			Timeout time.Duration `json:"timeout"`
+			Ports   []int         `json:"ports"`
+		} `json:"db"`
+	}
+
+	files := map[string]string{
+		"values.json": "{\n  \"name\": \"app\",\n  \"db\": {\"timeout\": 5, \"ports\": [1, \"x\"]}\n}",
+		"values.yml":  "name: app\ndb:\n  timeout: 5\n  ports: [1, x]\n",
+		"values.toml": "name = \"app\"\n\n[db]\n  timeout = 5\n  ports = [\"1\", \"x\"]\n",
+	}
+	positions := map[string][2]string{
+		"values.json": {"line 3, column 21: ", "line 3, column 33: "},
+		"values.yml":  {"line 3, column 12: ", "line 4, column 10: "},
+		"values.toml": {"line 4, column 13: ", "line 5, column 11: "},
+	}
+
+	for name, content := range files {
+		filename := writeConfigFile(t, name, content)
+		defer os.Remove(filename)
+
+		err := Load(&config{}, WithFile(filename), WithEnv(false))
+		if !assert.IsType(t, Errors{}, err, name) || !assert.Len(t, err.(Errors), 2, name) {
+			continue
+		}
+		errs := err.(Errors)
+		assert.Equal(t, "db.timeout", errs[0].Field, name)
+		assert.Equal(t, "5", errs[0].Value, name)
+		assert.Equal(t, "time.Duration", errs[0].Type, name)
+		assert.Contains(t, errs[0].Error(), positions[name][0], name)
+		assert.Equal(t, "db.ports", errs[1].Field, name)
+		assert.Equal(t, "", errs[1].Value, name)
+		assert.Contains(t, errs[1].Error(), "expected []int: "+positions[name][1]+"item 1: invalid syntax", name)
+	}
+}
+
+func TestParseDotenvLine(t *testing.T) {
+	for line, expected := range map[string][2]string{
+		"KEY=value":             {"KEY", "value"},
+		"  export KEY = value ": {"KEY", "value"},
+		"KEY=":                  {"KEY", ""},
+		`KEY="a\nb \"c\""`:      {"KEY", "a\nb \"c\""},
+		`KEY='a\nb' # comment`:  {"KEY", `a\nb`},
+		"KEY=a#b # comment":     {"KEY", "a#b"},
+		"# comment":             {"", ""},
+	} {
+		key, value, _, err := parseDotenvLine(line)
+		if assert.NoError(t, err, line) {
+			assert.Equal(t, expected, [2]string{key, value}, line)
+		}
+	}
+
+	for line, col := range map[string]int{
+		"KEY":           1,
+		"  BAD KEY=1":   3,
+		`KEY="a" b`:     9,
+		"KEY='unclosed": 5,
+	} {
+		_, _, c, err := parseDotenvLine(line)
+		if assert.Error(t, err, line) {
+			assert.Equal(t, col, c, line)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"time"
+)
+
+type (
+	// Option configures `Load()`.
+	Option func(*loader)
+
+	loader struct {
+		defaults     interface{}
+		files        []file
+		env          bool
+		envSeparator string
+		flags        *flag.FlagSet
+		provenance   Provenance
+		validate     bool
+		pollInterval time.Duration
+	}
+
+	file struct {
+		name   string
+		format Format
+	}
+)
+
+var configType = reflect.TypeOf(Config{})
+
+// WithDefaults sets default values. `defaults` must be a struct of
+// the target type or a pointer to it. Without defaults, current values
+// of the target are used.
+func WithDefaults(defaults interface{}) Option {
+	return func(l *loader) {
+		l.defaults = defaults
+	}
+}
+
+// WithFile adds a file. Files are loaded in order, so values of later
+// files override earlier ones. Empty filenames are skipped. The format
+// is detected by the extension: .json, .yml or .yaml, .toml and .env
+// or files named `.env.*`. Other files are read as JSON.
+func WithFile(filename string) Option {
+	return WithFileFormat(filename, detectFormat(filename))
+}
+
+// WithFileFormat adds a file in the given format. JSON, YAML and TOML
+// keys match json tags and values are decoded like env variables, so
+// "5s" is a valid `time.Duration`. Dotenv variables are matched like
+// env variables.
+func WithFileFormat(filename string, format Format) Option {
+	return func(l *loader) {
+		if filename != "" {
+			l.files = append(l.files, file{filename, format})
+		}
+	}
+}
+
+// WithEnv sets whether env variables are read. They are read
+// by default, after files.
+func WithEnv(enabled bool) Option {
+	return func(l *loader) {
+		l.env = enabled
+	}
+}
+
+// WithEnvSeparator sets a separator of nested field names in env
+// variable names. It defaults to `DefaultEnvSeparator`.
+func WithEnvSeparator(separator string) Option {
+	return func(l *loader) {
+		l.envSeparator = separator
+	}
+}
+
+// WithValidation sets whether the loaded config is checked with
+// `Validate()`. It's checked by default, if it was loaded without
+// errors.
+func WithValidation(enabled bool) Option {
+	return func(l *loader) {
+		l.validate = enabled
+	}
+}
+
+// WithFlags sets flags read after env variables. Only flags set on
+// the command line are used. A flag matches a field by its name from
+// `BindFlags()`, like "db-host", or by its path, like "db.host". Other
+// flags are ignored. `fs` must be parsed before `Load()` is called.
+func WithFlags(fs *flag.FlagSet) Option {
+	return func(l *loader) {
+		l.flags = fs
+	}
+}
+
+// Load populates `target`, a pointer to any struct, from defaults,
+// files, secret files, env variables and flags, in that order, so
+// values of later sources override earlier ones. Defaults are the
+// current values of `target` or `WithDefaults()`; zero fields then get
+// values of `default` tags:
+//
+//	Port int `json:"port" default:"8080"`
+//
+// A `secret` tag sets a file, like "/run/secrets/db_password", which
+// the field is read from if it exists. Secret fields and fields of the
+// `Secret` type are redacted in errors and `Dump()`.
+//
+// Nil pointers to embedded structs are allocated. If `target` is
+// or embeds `Config`, its `ConfigFilePath` is set to the last loaded
+// file. Use `WithProvenance()` to find out which source set each field.
+//
+// A file which can't be read is returned as is. Invalid values in tags,
+// files, env variables and flags are collected and returned together
+// as `Errors`. Otherwise, `target` is checked with `Validate()`.
+func Load(target interface{}, opts ...Option) error {
+	l := loader{env: true, envSeparator: DefaultEnvSeparator, validate: true}
+	for _, opt := range opts {
+		opt(&l)
+	}
+	if l.provenance == nil && l.validate {
+		l.provenance = Provenance{}
+	}
+
+	v, err := structValue(target)
+	if err != nil {
+		return err
+	}
+
+	if l.defaults != nil {
+		defaults := reflect.Indirect(reflect.ValueOf(l.defaults))
+		if defaults.Type() != v.Type() {
+			return errors.New("config: defaults must be of type " + v.Type().String())
+		}
+		v.Set(defaults)
+	}
+	allocEmbedded(v)
+
+	errs := l.loadDefaults(v)
+	for _, f := range l.files {
+		data, err := ioutil.ReadFile(f.name)
+		if err != nil {
+			return err
+		}
+
+		errs = append(errs, l.loadFile(v, f, data)...)
+	}
+
+	errs = append(errs, l.loadSecrets(v)...)
+
+	if l.env {
+		r := l.envReader(Source{Kind: SourceEnv}, os.LookupEnv)
+		r.readStruct(v)
+		errs = append(errs, r.errs...)
+	}
+
+	if l.flags != nil {
+		errs = append(errs, l.loadFlags(v)...)
+	}
+
+	if len(errs) == 0 && l.validate {
+		return Validate(target, l.provenance)
+	}
+	return errs.err()
+}
+
+// loadDefaults marks all fields as defaults and sets zero fields
+// to values of their `default` tags.
+func (l *loader) loadDefaults(v reflect.Value) Errors {
+	var errs Errors
+	walkFields(v, l.envSeparator, func(f fieldInfo) {
+		l.provenance.set(f.Path, Source{Kind: SourceDefault})
+
+		value, ok := f.Field.Tag.Lookup("default")
+		if !ok || !isZero(f.Value) {
+			return
+		}
+
+		source := Source{Kind: SourceDefault, Name: "tag"}
+		if err := decodeField(f, value); err != nil {
+			errs = append(errs, fieldError(f, source, value, err))
+			return
+		}
+		l.provenance.set(f.Path, source)
+	})
+	return errs
+}
+
+// structValue returns the struct `target` points to.
+func structValue(target interface{}) (reflect.Value, error) {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, errors.New("config: target must be a non-nil pointer to a struct")
+	}
+	return v.Elem(), nil
+}
+
+// isZero returns true if `v` is the zero value of its type.
+func isZero(v reflect.Value) bool {
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
+
+// allocEmbedded allocates nil pointers to embedded structs.
+func allocEmbedded(v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		structField := v.Type().Field(i)
+		if !structField.Anonymous || !field.CanSet() {
+			continue
+		}
+
+		if field.Kind() == reflect.Ptr && field.Type().Elem().Kind() == reflect.Struct {
+			if field.IsNil() {
+				field.Set(reflect.New(field.Type().Elem()))
+			}
+			field = field.Elem()
+		}
+		if field.Kind() == reflect.Struct {
+			allocEmbedded(field)
+		}
+	}
+}
+
+// embeddedConfig returns `Config` which is `v` or is embedded in `v`.
+func embeddedConfig(v reflect.Value) *Config {
+	if v.Type() == configType {
+		return v.Addr().Interface().(*Config)
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if !v.Type().Field(i).Anonymous || !field.CanSet() {
+			continue
+		}
+
+		if field.Kind() == reflect.Ptr {
+			if field.IsNil() {
+				continue
+			}
+			field = field.Elem()
+		}
+		if field.Kind() == reflect.Struct {
+			if c := embeddedConfig(field); c != nil {
+				return c
+			}
+		}
+	}
+
+	return nil
+}
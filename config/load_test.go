@@ -0,0 +1,91 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	appConfig struct {
+		*Config
+		Database
+		Name string `json:"name"`
+		Port int    `json:"port"`
+	}
+
+	Database struct {
+		DSN string `json:"dsn"`
+	}
+)
+
+func writeTempFile(t *testing.T, content string) string {
+	f, err := ioutil.TempFile("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
+
+func TestLoad(t *testing.T) {
+	first := writeTempFile(t, `{"name":"app","port":80,"debug":true}`)
+	defer os.Remove(first)
+	second := writeTempFile(t, `{"port":8080,"dsn":"postgres://db"}`)
+	defer os.Remove(second)
+
+	os.Setenv("NAME", "from-env")
+	defer os.Unsetenv("NAME")
+
+	c := &appConfig{}
+	err := Load(c, WithDefaults(appConfig{Port: 1}), WithFile(first), WithFile(second))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "from-env", c.Name)
+		assert.Equal(t, 8080, c.Port)
+		assert.Equal(t, "postgres://db", c.DSN)
+		if assert.NotNil(t, c.Config) {
+			assert.True(t, c.Debug)
+			assert.Equal(t, second, c.ConfigFilePath)
+		}
+	}
+}
+
+func TestLoadWithoutEnv(t *testing.T) {
+	os.Setenv("PORT", "9000")
+	defer os.Unsetenv("PORT")
+
+	c := &appConfig{Port: 1}
+	if assert.NoError(t, Load(c, WithEnv(false))) {
+		assert.Equal(t, 1, c.Port)
+		assert.NotNil(t, c.Config)
+	}
+}
+
+func TestLoadCollectsErrors(t *testing.T) {
+	file := writeTempFile(t, `{"port":"eighty"}`)
+	defer os.Remove(file)
+
+	os.Setenv("DEBUG", "maybe")
+	defer os.Unsetenv("DEBUG")
+
+	err := Load(&appConfig{}, WithFile(file))
+	if assert.IsType(t, Errors{}, err) {
+		errs := err.(Errors)
+		if assert.Len(t, errs, 2) {
+			assert.Equal(t, "port", errs[0].Field)
+			assert.Equal(t, "debug", errs[1].Field)
+		}
+	}
+}
+
+func TestLoadInvalidTarget(t *testing.T) {
+	assert.Error(t, Load(appConfig{}))
+	assert.Error(t, Load((*appConfig)(nil)))
+	assert.Error(t, Load(&appConfig{}, WithDefaults(Config{})))
+}
@@ -0,0 +1,303 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// textPos is a line and a column in a file, both starting at 1.
+type textPos struct {
+	line, col int
+}
+
+var yamlLineRe = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+// filePositions returns positions of values in a file by lowercase
+// field paths, like "db.host". YAML and TOML files are scanned line
+// by line, so values in flow mappings and inline tables aren't found.
+func filePositions(data []byte, format Format) map[string]textPos {
+	switch format {
+	case FormatJSON:
+		s := jsonScanner{data: data, positions: make(map[string]textPos)}
+		s.value("")
+		return s.positions
+	case FormatYAML:
+		return yamlPositions(string(data))
+	case FormatTOML:
+		return tomlPositions(string(data))
+	}
+	return nil
+}
+
+// lookupPosition returns a position of the value at `path` or of its
+// closest parent which was found.
+func lookupPosition(positions map[string]textPos, path string) (textPos, bool) {
+	path = strings.ToLower(path)
+	for path != "" {
+		if pos, ok := positions[path]; ok {
+			return pos, true
+		}
+
+		i := strings.LastIndex(path, ".")
+		if i == -1 {
+			break
+		}
+		path = path[:i]
+	}
+	return textPos{}, false
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// yamlError adds a column to YAML syntax errors. yaml.v2 reports only
+// lines, so the column is of the first character on the line.
+func yamlError(data []byte, err error) error {
+	m := yamlLineRe.FindStringSubmatch(err.Error())
+	if m == nil {
+		return err
+	}
+
+	n, _ := strconv.Atoi(m[1])
+	lines := strings.Split(string(data), "\n")
+	col := 1
+	if n >= 1 && n <= len(lines) {
+		line := lines[n-1]
+		col = len(line) - len(strings.TrimLeft(line, " \t")) + 1
+	}
+	return fmt.Errorf("line %d, column %d: %s", n, col, m[2])
+}
+
+// jsonScanner finds positions of values in valid JSON.
+type jsonScanner struct {
+	data      []byte
+	i         int
+	positions map[string]textPos
+}
+
+func (s *jsonScanner) value(path string) {
+	s.skipSpace()
+	if s.i >= len(s.data) {
+		return
+	}
+	if path != "" {
+		line, col := position(s.data, int64(s.i))
+		s.positions[path] = textPos{line, col}
+	}
+
+	switch s.data[s.i] {
+	case '{':
+		s.i++
+		for {
+			s.skipSpace()
+			if s.i >= len(s.data) || s.data[s.i] == '}' {
+				s.i++
+				return
+			}
+			if s.data[s.i] == ',' {
+				s.i++
+				continue
+			}
+
+			var key string
+			json.Unmarshal(s.data[s.i:s.stringEnd()], &key)
+			s.i = s.stringEnd()
+			s.skipSpace()
+			if s.i < len(s.data) && s.data[s.i] == ':' {
+				s.i++
+			}
+			s.value(joinPath(path, strings.ToLower(key)))
+		}
+	case '[':
+		s.i++
+		for {
+			s.skipSpace()
+			if s.i >= len(s.data) || s.data[s.i] == ']' {
+				s.i++
+				return
+			}
+			if s.data[s.i] == ',' {
+				s.i++
+				continue
+			}
+			s.value(path + "[]")
+		}
+	case '"':
+		s.i = s.stringEnd()
+	default:
+		for s.i < len(s.data) && !strings.ContainsRune(",}] \t\r\n", rune(s.data[s.i])) {
+			s.i++
+		}
+	}
+}
+
+// stringEnd returns an index after the string starting at `s.i`.
+func (s *jsonScanner) stringEnd() int {
+	for i := s.i + 1; i < len(s.data); i++ {
+		switch s.data[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return len(s.data)
+}
+
+func (s *jsonScanner) skipSpace() {
+	for s.i < len(s.data) && strings.ContainsRune(" \t\r\n", rune(s.data[s.i])) {
+		s.i++
+	}
+}
+
+// yamlPositions finds keys of block mappings by their indentation.
+// Items of lists get the "[]" suffix, so they don't match fields.
+func yamlPositions(data string) map[string]textPos {
+	type parent struct {
+		indent int
+		path   string
+	}
+
+	positions := make(map[string]textPos)
+	var parents []parent
+	blockIndent := -1
+
+	for n, line := range strings.Split(data, "\n") {
+		content := strings.TrimLeft(line, " ")
+		indent := len(line) - len(content)
+		trimmed := strings.TrimSpace(content)
+		if trimmed == "" || trimmed[0] == '#' {
+			continue
+		}
+		// Lines of block scalars, like `key: |`.
+		if blockIndent >= 0 && indent > blockIndent {
+			continue
+		}
+		blockIndent = -1
+		if strings.HasPrefix(trimmed, "---") || strings.HasPrefix(trimmed, "...") {
+			parents = nil
+			continue
+		}
+
+		for len(parents) > 0 && parents[len(parents)-1].indent >= indent {
+			parents = parents[:len(parents)-1]
+		}
+		path := ""
+		if len(parents) > 0 {
+			path = parents[len(parents)-1].path
+		}
+
+		if content[0] == '-' {
+			parents = append(parents, parent{indent, path + "[]"})
+			continue
+		}
+
+		key, rest, ok := yamlKey(content)
+		if !ok {
+			continue
+		}
+		path = joinPath(path, strings.ToLower(key))
+		parents = append(parents, parent{indent, path})
+
+		value := strings.TrimLeft(rest, " ")
+		col := len(line) - len(value) + 1
+		if value == "" || value[0] == '#' {
+			col = indent + 1
+		} else if value[0] == '|' || value[0] == '>' {
+			blockIndent = indent
+		}
+		positions[path] = textPos{n + 1, col}
+	}
+
+	return positions
+}
+
+// yamlKey splits a line of a mapping into a key and the rest after
+// the colon.
+func yamlKey(content string) (string, string, bool) {
+	if content[0] == '"' || content[0] == '\'' {
+		end := strings.IndexByte(content[1:], content[0])
+		if end == -1 || !strings.HasPrefix(content[end+2:], ":") {
+			return "", "", false
+		}
+		return content[1 : end+1], content[end+3:], true
+	}
+
+	i := strings.Index(content, ": ")
+	if i == -1 {
+		if !strings.HasSuffix(content, ":") {
+			return "", "", false
+		}
+		i = len(content) - 1
+	}
+	return strings.TrimSpace(content[:i]), content[i+1:], true
+}
+
+// tomlPositions finds keys of tables and key/value pairs.
+func tomlPositions(data string) map[string]textPos {
+	positions := make(map[string]textPos)
+	table := ""
+	multiline := ""
+
+	for n, line := range strings.Split(data, "\n") {
+		if multiline != "" {
+			if strings.Contains(line, multiline) {
+				multiline = ""
+			}
+			continue
+		}
+
+		content := strings.TrimLeft(line, " \t")
+		col := len(line) - len(content) + 1
+		if content == "" || content[0] == '#' {
+			continue
+		}
+
+		if content[0] == '[' {
+			end := strings.LastIndex(content, "]")
+			if end == -1 {
+				continue
+			}
+			array := strings.HasPrefix(content, "[[")
+			table = tomlKey(strings.Trim(content[:end+1], "[]"))
+			positions[table] = textPos{n + 1, col}
+			if array {
+				table += "[]"
+			}
+			continue
+		}
+
+		eq := strings.Index(content, "=")
+		if eq == -1 {
+			continue
+		}
+		path := joinPath(table, tomlKey(content[:eq]))
+		value := strings.TrimLeft(content[eq+1:], " \t")
+		positions[path] = textPos{n + 1, len(line) - len(value) + 1}
+
+		for _, quotes := range []string{`"""`, "'''"} {
+			if strings.HasPrefix(value, quotes) && !strings.Contains(value[3:], quotes) {
+				multiline = quotes
+			}
+		}
+	}
+
+	return positions
+}
+
+// tomlKey converts a dotted key, which can have quoted parts, to
+// a lowercase path.
+func tomlKey(key string) string {
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		parts[i] = strings.ToLower(strings.Trim(strings.TrimSpace(part), `"'`))
+	}
+	return strings.Join(parts, ".")
+}
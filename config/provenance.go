@@ -0,0 +1,115 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Kinds of sources in the order of precedence used by `Load()`.
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+type (
+	// Source describes where a config value comes from.
+	Source struct {
+		// Kind is one of `SourceDefault`, `SourceFile`, `SourceEnv`
+		// and `SourceFlag`.
+		Kind string
+
+		// Name is a file name or "tag" for struct tag defaults.
+		Name string
+
+		// Key is an env variable or a flag name.
+		Key string
+	}
+
+	// Provenance maps field paths, like "db.host", to sources of their
+	// final values. Use `WithProvenance()` to get it from `Load()`.
+	Provenance map[string]Source
+)
+
+func (s Source) String() string {
+	parts := []string{s.Kind}
+	if s.Name != "" {
+		parts = append(parts, s.Name)
+	}
+	if s.Key != "" {
+		parts = append(parts, s.Key)
+	}
+	return strings.Join(parts, " ")
+}
+
+// errorSource is used as `FieldError.Source`.
+func (s Source) errorSource() string {
+	if s.Kind == SourceFile {
+		return s.Name
+	}
+	return s.Kind
+}
+
+// WithProvenance fills `p` with sources of all fields. Fields which
+// no source changed have the `SourceDefault` kind.
+func WithProvenance(p Provenance) Option {
+	return func(l *loader) {
+		l.provenance = p
+	}
+}
+
+func (p Provenance) set(path string, source Source) {
+	if p != nil {
+		p[path] = source
+	}
+}
+
+// String returns a table of fields and their sources sorted by field.
+func (p Provenance) String() string {
+	paths := make([]string, 0, len(p))
+	for path := range p {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "FIELD\tSOURCE")
+	for _, path := range paths {
+		fmt.Fprintf(tw, "%s\t%s\n", path, p[path])
+	}
+	tw.Flush()
+
+	return buf.String()
+}
+
+// lookupPath returns a value of a decoded object at the field path.
+// Keys are matched case-insensitively like `encoding/json` does.
+func lookupPath(v interface{}, path string) (interface{}, bool) {
+	for _, name := range strings.Split(path, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		value, found := m[name]
+		if !found {
+			for k, item := range m {
+				if strings.EqualFold(k, name) {
+					value, found = item, true
+					break
+				}
+			}
+		}
+		if !found {
+			return nil, false
+		}
+		v = value
+	}
+
+	return v, true
+}
@@ -0,0 +1,114 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type layeredConfig struct {
+	Name    string `json:"name" default:"app"`
+	Port    int    `json:"port" default:"8080"`
+	Timeout int    `json:"timeout"`
+	DB      struct {
+		Host string `json:"host" default:"localhost"`
+		User string `json:"user"`
+	} `json:"db"`
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	filename := writeTempFile(t, `{"port":80,"timeout":5,"db":{"host":"db","user":"file"}}`)
+	defer os.Remove(filename)
+
+	os.Setenv("TIMEOUT", "10")
+	defer os.Unsetenv("TIMEOUT")
+	os.Setenv("DB_USER", "env")
+	defer os.Unsetenv("DB_USER")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("db-user", "", "")
+	fs.Int("port", 0, "")
+	fs.Bool("verbose", false, "")
+	assert.NoError(t, fs.Parse([]string{"-db-user", "flag", "-verbose"}))
+
+	c := &layeredConfig{}
+	p := Provenance{}
+	err := Load(c, WithFile(filename), WithFlags(fs), WithProvenance(p))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "app", c.Name)
+		assert.Equal(t, 80, c.Port)
+		assert.Equal(t, 10, c.Timeout)
+		assert.Equal(t, "db", c.DB.Host)
+		assert.Equal(t, "flag", c.DB.User)
+
+		assert.Equal(t, Provenance{
+			"name":    {Kind: SourceDefault, Name: "tag"},
+			"port":    {Kind: SourceFile, Name: filename},
+			"timeout": {Kind: SourceEnv, Key: "TIMEOUT"},
+			"db.host": {Kind: SourceFile, Name: filename},
+			"db.user": {Kind: SourceFlag, Key: "db-user"},
+		}, p)
+	}
+}
+
+func TestLoadDefaultTags(t *testing.T) {
+	c := &layeredConfig{Port: 1}
+	p := Provenance{}
+	err := Load(c, WithEnv(false), WithProvenance(p))
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1, c.Port, "non-zero values are kept")
+		assert.Equal(t, "localhost", c.DB.Host)
+		assert.Equal(t, Source{Kind: SourceDefault}, p["port"])
+		assert.Equal(t, Source{Kind: SourceDefault}, p["timeout"])
+		assert.Equal(t, Source{Kind: SourceDefault, Name: "tag"}, p["db.host"])
+	}
+
+	var invalid struct {
+		Port int `json:"port" default:"http"`
+	}
+	err = Load(&invalid, WithEnv(false))
+	if assert.IsType(t, Errors{}, err) {
+		assert.Equal(t, "port", err.(Errors)[0].Field)
+		assert.Equal(t, SourceDefault, err.(Errors)[0].Source)
+	}
+}
+
+func TestLoadFlagErrors(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("port", "", "")
+	assert.NoError(t, fs.Parse([]string{"-port", "http"}))
+
+	err := Load(&layeredConfig{}, WithEnv(false), WithFlags(fs))
+	if assert.IsType(t, Errors{}, err) {
+		e := err.(Errors)[0]
+		assert.Equal(t, "port", e.Field)
+		assert.Equal(t, SourceFlag, e.Source)
+		assert.Equal(t, "port", e.Key)
+	}
+}
+
+func TestProvenanceString(t *testing.T) {
+	p := Provenance{
+		"port":    {Kind: SourceFile, Name: "config.yml"},
+		"db.user": {Kind: SourceEnv, Key: "DB_USER"},
+	}
+	assert.Equal(t, "FIELD    SOURCE\n"+
+		"db.user  env DB_USER\n"+
+		"port     file config.yml\n", p.String())
+}
+
+func TestLookupPath(t *testing.T) {
+	v := map[string]interface{}{
+		"DB": map[string]interface{}{"host": "db"},
+	}
+	value, ok := lookupPath(v, "db.host")
+	assert.True(t, ok)
+	assert.Equal(t, "db", value)
+
+	_, ok = lookupPath(v, "db.user")
+	assert.False(t, ok)
+	_, ok = lookupPath(v, "db.host.name")
+	assert.False(t, ok)
+}
@@ -0,0 +1,155 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	// FileEnvSuffix is added to env variable names to read values
+	// from files, for example `DB_PASSWORD_FILE=/run/secrets/db_password`.
+	FileEnvSuffix = "_FILE"
+
+	// Redacted replaces values of secret fields in dumps and errors.
+	Redacted = "[redacted]"
+)
+
+// Secret is a string which is redacted when printed or encoded
+// to JSON. Use `string(s)` to get the value.
+type Secret string
+
+var secretType = reflect.TypeOf(Secret(""))
+
+func (s Secret) String() string {
+	return Redacted
+}
+
+// GoString redacts the value in `%#v` output.
+func (s Secret) GoString() string {
+	return fmt.Sprintf("config.Secret(%q)", Redacted)
+}
+
+// MarshalJSON redacts the value in JSON output.
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + Redacted + `"`), nil
+}
+
+// isSecret returns true for fields of the `Secret` type or with
+// a `secret` tag.
+func isSecret(f fieldInfo) bool {
+	if _, ok := f.Field.Tag.Lookup("secret"); ok {
+		return true
+	}
+
+	t := f.Value.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t == secretType
+}
+
+// rawField formats the field value like `formatField()`, but doesn't
+// redact `Secret` values.
+func rawField(f fieldInfo) string {
+	if v := reflect.Indirect(f.Value); v.IsValid() && v.Type() == secretType {
+		return v.String()
+	}
+	return formatField(f)
+}
+
+// safeValue redacts values of secret fields.
+func safeValue(f fieldInfo, value string) string {
+	if isSecret(f) {
+		return redact(value)
+	}
+	return value
+}
+
+// redact replaces a non-empty value with `Redacted`.
+func redact(value string) string {
+	if value == "" {
+		return ""
+	}
+	return Redacted
+}
+
+// readSecretFile reads a value from a file. Trailing newlines are
+// removed, as files are usually created with `echo` or editors.
+func readSecretFile(filename string) (string, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// loadSecrets reads fields from files set in `secret` tags. Missing
+// files are skipped, so secrets can be given by other sources
+// in development.
+func (l *loader) loadSecrets(v reflect.Value) Errors {
+	var errs Errors
+	walkFields(v, l.envSeparator, func(f fieldInfo) {
+		filename := f.Field.Tag.Get("secret")
+		if filename == "" {
+			return
+		}
+
+		source := Source{Kind: SourceFile, Name: filename}
+		value, err := readSecretFile(filename)
+		if os.IsNotExist(err) {
+			return
+		}
+		if err != nil {
+			errs = append(errs, &FieldError{Field: f.Path, Source: filename, Err: err})
+			return
+		}
+		if err := decodeField(f, value); err != nil {
+			errs = append(errs, fieldError(f, source, value, err))
+			return
+		}
+		l.provenance.set(f.Path, source)
+	})
+	return errs
+}
+
+// Dump returns a table of fields of `target`, a pointer to a struct,
+// with values of secret fields redacted. It's safe to log. If `p` isn't
+// nil, sources of values are listed as well. Fields with a `secret` tag
+// are plain strings, so configs having them can implement `String()`
+// with `Dump()` to be redacted when printed.
+func Dump(target interface{}, p Provenance) string {
+	v, err := structValue(target)
+	if err != nil {
+		return err.Error()
+	}
+
+	var rows []string
+	walkFields(v, DefaultEnvSeparator, func(f fieldInfo) {
+		row := f.Path + "\t" + safeValue(f, formatField(f))
+		if p != nil {
+			row += "\t" + p[f.Path].String()
+		}
+		rows = append(rows, row)
+	})
+	sort.Strings(rows)
+
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	if p != nil {
+		fmt.Fprintln(tw, "FIELD\tVALUE\tSOURCE")
+	} else {
+		fmt.Fprintln(tw, "FIELD\tVALUE")
+	}
+	for _, row := range rows {
+		fmt.Fprintln(tw, row)
+	}
+	tw.Flush()
+
+	return buf.String()
+}
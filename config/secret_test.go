@@ -0,0 +1,148 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type secretConfig struct {
+	User     string `json:"user"`
+	Password string `json:"password" secret:""`
+	Token    Secret `json:"token"`
+	Port     int    `json:"port"`
+}
+
+// String redacts the tagged password when the config is printed.
+func (c secretConfig) String() string {
+	return Dump(&c, nil)
+}
+
+func TestLoadFileEnv(t *testing.T) {
+	filename := writeTempFile(t, "s3cret\n")
+	defer os.Remove(filename)
+
+	os.Setenv("PASSWORD_FILE", filename)
+	defer os.Unsetenv("PASSWORD_FILE")
+	os.Setenv("TOKEN_FILE", filename)
+	defer os.Unsetenv("TOKEN_FILE")
+
+	c := &secretConfig{}
+	p := Provenance{}
+	if assert.NoError(t, Load(c, WithProvenance(p))) {
+		assert.Equal(t, "s3cret", c.Password)
+		assert.Equal(t, Secret("s3cret"), c.Token)
+		assert.Equal(t, Source{Kind: SourceEnv, Key: "PASSWORD_FILE"}, p["password"])
+	}
+
+	os.Setenv("PASSWORD", "other")
+	defer os.Unsetenv("PASSWORD")
+	os.Setenv("PORT_FILE", filename)
+	defer os.Unsetenv("PORT_FILE")
+	os.Setenv("USER_FILE", filename+".missing")
+	defer os.Unsetenv("USER_FILE")
+
+	err := LoadEnv(&secretConfig{})
+	if assert.IsType(t, Errors{}, err) && assert.Len(t, err.(Errors), 3) {
+		errs := err.(Errors)
+		assert.Equal(t, "user", errs[0].Field)
+		assert.Equal(t, "USER_FILE", errs[0].Key)
+		assert.EqualError(t, errs[1], "config: password: env PASSWORD_FILE: PASSWORD is set as well")
+		assert.Equal(t, "port", errs[2].Field)
+		assert.Equal(t, Redacted, errs[2].Value)
+		assert.NotContains(t, err.Error(), "s3cret")
+	}
+}
+
+func TestLoadSecretTag(t *testing.T) {
+	// Tags have relative paths, so the test runs in a temporary directory.
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	if err := ioutil.WriteFile("test_password.secret", []byte("s3cret\r\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var c struct {
+		Password string `json:"password" secret:"test_password.secret"`
+		Missing  string `json:"missing" secret:"test_missing.secret"`
+		Port     int    `json:"port" secret:"test_password.secret"`
+	}
+	c.Missing = "default"
+	p := Provenance{}
+	err = Load(&c, WithEnv(false), WithProvenance(p))
+	if assert.IsType(t, Errors{}, err) && assert.Len(t, err.(Errors), 1) {
+		e := err.(Errors)[0]
+		assert.Equal(t, "port", e.Field)
+		assert.Equal(t, Redacted, e.Value)
+		assert.NotContains(t, e.Error(), "s3cret")
+	}
+	assert.Equal(t, "s3cret", c.Password)
+	assert.Equal(t, "default", c.Missing)
+	assert.Equal(t, Source{Kind: SourceFile, Name: "test_password.secret"}, p["password"])
+
+	os.Setenv("PASSWORD", "from-env")
+	defer os.Unsetenv("PASSWORD")
+	c.Port = 0
+	assert.Error(t, Load(&c, WithProvenance(p)))
+	assert.Equal(t, "from-env", c.Password)
+}
+
+func TestRedaction(t *testing.T) {
+	c := &secretConfig{User: "admin", Password: "s3cret", Token: "t0ken", Port: 80}
+
+	for _, s := range []string{
+		fmt.Sprintf("%v", c.Token),
+		fmt.Sprintf("%+v", *c),
+		fmt.Sprintf("%#v", c.Token),
+		Dump(c, nil),
+		Dump(c, Provenance{"port": {Kind: SourceFlag, Key: "port"}}),
+	} {
+		assert.NotContains(t, s, "t0ken")
+	}
+	assert.NotContains(t, fmt.Sprintf("%+v", *c), "s3cret")
+	assert.NotContains(t, fmt.Sprintf("%v", c), "s3cret")
+	assert.Equal(t, "t0ken", string(c.Token))
+
+	data, err := json.Marshal(c)
+	if assert.NoError(t, err) {
+		assert.Contains(t, string(data), `"token":"[redacted]"`)
+	}
+
+	assert.Equal(t, "FIELD     VALUE\n"+
+		"password  [redacted]\n"+
+		"port      80\n"+
+		"token     [redacted]\n"+
+		"user      admin\n", Dump(c, nil))
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	if assert.NoError(t, BindFlags(fs, c)) {
+		assert.Equal(t, "", fs.Lookup("password").DefValue)
+		assert.Equal(t, "admin", fs.Lookup("user").DefValue)
+	}
+}
+
+func TestValidateSecret(t *testing.T) {
+	var c struct {
+		Token Secret `json:"token" validate:"min=8,regexp=^[a-z]+$"`
+	}
+	c.Token = "S3"
+	err := Validate(&c, nil)
+	if assert.IsType(t, Errors{}, err) && assert.Len(t, err.(Errors), 2) {
+		assert.Equal(t, Redacted, err.(Errors)[0].Value)
+		assert.NotContains(t, err.Error(), "S3")
+	}
+}
@@ -0,0 +1,234 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrRequired is returned for empty fields with the `required` rule.
+var ErrRequired = errors.New("value is required")
+
+// Validator is implemented by configs which check themselves after
+// validation tags. It can return `Errors` to report several fields.
+type Validator interface {
+	Validate() error
+}
+
+// Validate checks fields of `target`, a pointer to a struct, against
+// rules in `validate` tags and then calls `Validate()` if `target` is
+// a `Validator`. Rules are separated with commas:
+//
+//	Port  int    `json:"port" validate:"required,min=1,max=65535"`
+//	Level string `json:"level" validate:"oneof=debug info error"`
+//	Name  string `json:"name" validate:"regexp=^[a-z]+(,[a-z]+)*$"`
+//
+// Supported rules:
+//
+//   - required: the value is not empty
+//   - min=N, max=N: bounds of numbers, durations and byte sizes or
+//     of the length of strings, slices and maps
+//   - oneof=A B C: the value is one of space-separated options
+//   - regexp=RE: the value matches RE; it must be the last rule
+//   - url: the value is an absolute URL
+//   - hostport: the value is "host:port"
+//   - file_exists: the value is a path to an existing file
+//
+// Rules other than required, min and max skip empty values. Sources
+// of values in errors are taken from `p`, which can be nil.
+// All violations are returned together as `Errors`.
+func Validate(target interface{}, p Provenance) error {
+	v, err := structValue(target)
+	if err != nil {
+		return err
+	}
+
+	var errs Errors
+	walkFields(v, DefaultEnvSeparator, func(f fieldInfo) {
+		rules := f.Field.Tag.Get("validate")
+		if rules == "" {
+			return
+		}
+
+		for _, err := range checkRules(f, rules) {
+			source := p[f.Path]
+			errs = append(errs, &FieldError{
+				Field:  f.Path,
+				Source: source.errorSource(),
+				Key:    source.Key,
+				Value:  safeValue(f, formatField(f)),
+				Err:    err,
+			})
+		}
+	})
+
+	if validator, ok := target.(Validator); ok {
+		switch err := validator.Validate().(type) {
+		case nil:
+		case Errors:
+			errs = append(errs, err...)
+		case *FieldError:
+			errs = append(errs, err)
+		default:
+			errs = append(errs, &FieldError{Source: "validate", Err: err})
+		}
+	}
+
+	return errs.err()
+}
+
+// checkRules returns errors of all rules the field breaks.
+func checkRules(f fieldInfo, rules string) []error {
+	var errs []error
+	for rules != "" {
+		var rule string
+		if strings.HasPrefix(rules, "regexp=") {
+			rule, rules = rules, ""
+		} else if i := strings.Index(rules, ","); i != -1 {
+			rule, rules = rules[:i], rules[i+1:]
+		} else {
+			rule, rules = rules, ""
+		}
+
+		name, arg := rule, ""
+		if i := strings.Index(rule, "="); i != -1 {
+			name, arg = rule[:i], rule[i+1:]
+		}
+		if err := checkRule(f, strings.TrimSpace(name), arg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+func checkRule(f fieldInfo, name, arg string) error {
+	v := reflect.Indirect(f.Value)
+	empty := isEmpty(f.Value)
+
+	switch name {
+	case "required":
+		if empty {
+			return ErrRequired
+		}
+		return nil
+	case "min", "max":
+		if !v.IsValid() {
+			return nil
+		}
+		return checkBound(v, name, arg)
+	case "oneof", "regexp", "url", "hostport", "file_exists":
+	default:
+		return fmt.Errorf("unknown rule %q", name)
+	}
+
+	if empty {
+		return nil
+	}
+	value := rawField(f)
+
+	switch name {
+	case "oneof":
+		for _, option := range strings.Fields(arg) {
+			if value == option {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %s", strings.Join(strings.Fields(arg), ", "))
+	case "regexp":
+		re, err := regexp.Compile(arg)
+		if err != nil {
+			return fmt.Errorf("invalid rule regexp: %v", err)
+		}
+		if !re.MatchString(value) {
+			return fmt.Errorf("must match %s", arg)
+		}
+	case "url":
+		u, err := url.Parse(value)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return errors.New("must be an absolute URL")
+		}
+	case "hostport":
+		_, port, err := net.SplitHostPort(value)
+		if err == nil {
+			_, err = strconv.ParseUint(port, 10, 16)
+		}
+		if err != nil {
+			return errors.New("must be host:port")
+		}
+	case "file_exists":
+		info, err := os.Stat(value)
+		if err != nil {
+			return errors.New("file does not exist")
+		}
+		if info.IsDir() {
+			return errors.New("must be a file, not a directory")
+		}
+	}
+
+	return nil
+}
+
+// checkBound compares numbers with a bound decoded like the field,
+// so durations and byte sizes can use units, for example "min=1s".
+// Strings, slices and maps compare their length.
+func checkBound(v reflect.Value, name, arg string) error {
+	var n, bound float64
+
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map:
+		limit, err := strconv.Atoi(arg)
+		if err != nil {
+			return fmt.Errorf("invalid rule %s=%s", name, arg)
+		}
+		n, bound = float64(v.Len()), float64(limit)
+		if name == "min" && n < bound {
+			return fmt.Errorf("length must be at least %d", limit)
+		}
+		if name == "max" && n > bound {
+			return fmt.Errorf("length must be at most %d", limit)
+		}
+		return nil
+	}
+
+	limit := reflect.New(v.Type()).Elem()
+	if err := decodeValue(limit, arg, DefaultDelimiter, DefaultValueDelimiter); err != nil {
+		return fmt.Errorf("invalid rule %s=%s", name, arg)
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, bound = float64(v.Int()), float64(limit.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, bound = float64(v.Uint()), float64(limit.Uint())
+	case reflect.Float32, reflect.Float64:
+		n, bound = v.Float(), limit.Float()
+	default:
+		return fmt.Errorf("rule %s is not supported by %s", name, v.Type())
+	}
+
+	if name == "min" && n < bound {
+		return fmt.Errorf("must be at least %s", arg)
+	}
+	if name == "max" && n > bound {
+		return fmt.Errorf("must be at most %s", arg)
+	}
+	return nil
+}
+
+// isEmpty returns true for zero values, nil pointers and empty
+// strings, slices and maps.
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr:
+		return v.IsNil() || isEmpty(v.Elem())
+	case reflect.String, reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return isZero(v)
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type validatedConfig struct {
+	Name    string        `json:"name" validate:"required,regexp=^[a-z]+(,[a-z]+)*$"`
+	Port    int           `json:"port" validate:"min=1,max=65535"`
+	Level   string        `json:"level" validate:"oneof=debug info error"`
+	Timeout time.Duration `json:"timeout" validate:"min=1s"`
+	Limit   ByteSize      `json:"limit" validate:"max=1MB"`
+	Hosts   []string      `json:"hosts" validate:"min=1"`
+	API     string        `json:"api" validate:"url"`
+	Addr    string        `json:"addr" validate:"hostport"`
+	CA      string        `json:"ca" validate:"file_exists"`
+	Min     int           `json:"min"`
+	Max     int           `json:"max"`
+}
+
+func (c *validatedConfig) Validate() error {
+	if c.Min > c.Max {
+		return &FieldError{Field: "min", Err: errors.New("must not exceed max")}
+	}
+	return nil
+}
+
+func validConfig() *validatedConfig {
+	return &validatedConfig{
+		Name:    "app,web",
+		Port:    80,
+		Level:   "info",
+		Timeout: time.Second,
+		Limit:   1000,
+		Hosts:   []string{"a"},
+		API:     "https://example.com/api",
+		Addr:    "localhost:80",
+		CA:      os.Args[0],
+	}
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(validConfig(), nil))
+
+	c := &validatedConfig{Level: "trace", Timeout: time.Millisecond, Limit: 2000000,
+		API: "example.com", Addr: "localhost", CA: os.TempDir(), Min: 2}
+	err := Validate(c, Provenance{"level": {Kind: SourceEnv, Key: "LEVEL"}})
+	if assert.IsType(t, Errors{}, err) {
+		errs := err.(Errors)
+		if assert.Len(t, errs, 10) {
+			assert.Equal(t, "name", errs[0].Field)
+			assert.Equal(t, ErrRequired, errs[0].Err)
+			assert.Equal(t, "config: port: value \"0\": must be at least 1", errs[1].Error())
+			assert.Equal(t, "config: level: env LEVEL: value \"trace\": must be one of debug, info, error", errs[2].Error())
+			assert.EqualError(t, errs[3].Err, "must be at least 1s")
+			assert.EqualError(t, errs[4].Err, "must be at most 1MB")
+			assert.EqualError(t, errs[5].Err, "length must be at least 1")
+			assert.EqualError(t, errs[6].Err, "must be an absolute URL")
+			assert.EqualError(t, errs[7].Err, "must be host:port")
+			assert.EqualError(t, errs[8].Err, "must be a file, not a directory")
+			assert.Equal(t, "config: min: must not exceed max", errs[9].Error())
+		}
+	}
+
+	c = validConfig()
+	c.Name = "App"
+	err = Validate(c, nil)
+	if assert.IsType(t, Errors{}, err) {
+		assert.EqualError(t, err.(Errors)[0].Err, "must match ^[a-z]+(,[a-z]+)*$")
+	}
+}
+
+func TestValidateRuleErrors(t *testing.T) {
+	var c struct {
+		Port int  `json:"port" validate:"min=x"`
+		Flag bool `json:"flag" validate:"positive,max=1"`
+	}
+	err := Validate(&c, nil)
+	if assert.IsType(t, Errors{}, err) {
+		errs := err.(Errors)
+		if assert.Len(t, errs, 3) {
+			assert.EqualError(t, errs[0].Err, "invalid rule min=x")
+			assert.EqualError(t, errs[1].Err, `unknown rule "positive"`)
+			assert.EqualError(t, errs[2].Err, "rule max is not supported by bool")
+		}
+	}
+}
+
+func TestLoadValidates(t *testing.T) {
+	filename := writeTempFile(t, `{"name":"app","port":70000,"level":"info"}`)
+	defer os.Remove(filename)
+	ioutil.WriteFile(filename+".ca", nil, 0600)
+	defer os.Remove(filename + ".ca")
+
+	os.Setenv("CA", filename+".ca")
+	defer os.Unsetenv("CA")
+
+	c := &validatedConfig{Timeout: time.Second, Hosts: []string{"a"}}
+	err := Load(c, WithFile(filename))
+	if assert.IsType(t, Errors{}, err) && assert.Len(t, err.(Errors), 1) {
+		e := err.(Errors)[0]
+		assert.Equal(t, "port", e.Field)
+		assert.Equal(t, filename, e.Source)
+		assert.Equal(t, "70000", e.Value)
+	}
+
+	c = &validatedConfig{}
+	assert.NoError(t, Load(c, WithFile(filename), WithValidation(false)))
+}
@@ -0,0 +1,359 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultPollInterval is used by `Watch()` when inotify isn't available.
+const DefaultPollInterval = time.Second
+
+// debounceDelay groups events of a single save, like writing
+// a temporary file and renaming it.
+const debounceDelay = 100 * time.Millisecond
+
+type (
+	// Watcher reloads a config when its files change. It's safe
+	// to use concurrently.
+	Watcher struct {
+		opts     []Option
+		files    []string
+		defaults reflect.Value
+		contents map[string][]byte
+		current  atomic.Value
+
+		mu          sync.Mutex
+		subscribers []func(Change)
+		err         error
+
+		done      chan struct{}
+		closeOnce sync.Once
+		wg        sync.WaitGroup
+	}
+
+	// Change is sent to subscribers of a `Watcher` after the config
+	// is reloaded. `Old` and `New` are pointers to configs of the type
+	// passed to `Watch()`.
+	Change struct {
+		Old, New interface{}
+		Fields   []FieldChange
+	}
+
+	// FieldChange is a field which has a different value after
+	// a reload. Values are formatted like env variables and values
+	// of secret fields are redacted.
+	FieldChange struct {
+		Field    string
+		Old, New string
+		Source   Source
+	}
+)
+
+// WithPolling makes `Watch()` poll files every `interval` instead
+// of using inotify, for example on network file systems.
+func WithPolling(interval time.Duration) Option {
+	return func(l *loader) {
+		l.pollInterval = interval
+	}
+}
+
+// Watch loads `target` like `Load()` and reloads the config when files
+// given with `WithFile()` change. The last file is the `ConfigFilePath`
+// of configs embedding `Config`. Directories of files are watched, so
+// files replaced by renaming, like editors save them, and Kubernetes
+// config maps, which swap symlinks, are reloaded as well. Outside of
+// Linux or if inotify isn't available or fails, files are polled every
+// `DefaultPollInterval`.
+//
+// Every reload creates a new config, which is checked with `Validate()`
+// and then swapped with the current one. An invalid config is rejected
+// and the current one is kept; the error is returned by `Err()`.
+// `target` isn't changed after the first load.
+func Watch(target interface{}, opts ...Option) (*Watcher, error) {
+	v, err := structValue(target)
+	if err != nil {
+		return nil, err
+	}
+
+	var l loader
+	for _, opt := range opts {
+		opt(&l)
+	}
+	if len(l.files) == 0 {
+		return nil, errors.New("config: no files to watch")
+	}
+
+	w := &Watcher{
+		opts:     opts,
+		contents: make(map[string][]byte),
+		done:     make(chan struct{}),
+	}
+	if l.defaults != nil {
+		w.defaults = deepCopy(reflect.Indirect(reflect.ValueOf(l.defaults)))
+	} else {
+		w.defaults = deepCopy(v)
+	}
+	for _, f := range l.files {
+		w.files = append(w.files, f.name)
+	}
+
+	// Contents are read before loading, so changes made in the meantime
+	// cause a reload.
+	w.readFiles()
+	if _, err := w.load(v); err != nil {
+		return nil, err
+	}
+	w.current.Store(target)
+
+	n := w.notifier(l.pollInterval)
+	interval := l.pollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	w.wg.Add(1)
+	go w.run(n, interval)
+
+	return w, nil
+}
+
+// notifier returns a notifier of changes in directories of files or
+// nil if files should be polled.
+func (w *Watcher) notifier(pollInterval time.Duration) *dirNotifier {
+	if pollInterval > 0 {
+		return nil
+	}
+
+	var dirs []string
+	seen := make(map[string]bool)
+	for _, name := range w.files {
+		dir := filepath.Dir(name)
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+
+	n, err := newDirNotifier(dirs)
+	if err != nil {
+		return nil
+	}
+	return n
+}
+
+// Current returns the current config. It must not be modified.
+func (w *Watcher) Current() interface{} {
+	return w.current.Load()
+}
+
+// Subscribe adds a function called after every reload which changes
+// the config. Functions are called in order from a single goroutine.
+func (w *Watcher) Subscribe(fn func(Change)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.subscribers = append(w.subscribers, fn)
+}
+
+// Err returns an error of the last reload or nil if it succeeded.
+func (w *Watcher) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.err
+}
+
+// Close stops watching files. Calling it again does nothing.
+func (w *Watcher) Close() error {
+	w.closeOnce.Do(func() {
+		close(w.done)
+		w.wg.Wait()
+	})
+	return nil
+}
+
+func (w *Watcher) run(n *dirNotifier, interval time.Duration) {
+	defer w.wg.Done()
+
+	var (
+		events   <-chan struct{}
+		errs     <-chan error
+		tick     <-chan time.Time
+		debounce <-chan time.Time
+	)
+	if n != nil {
+		defer n.Close()
+		events, errs = n.events, n.errs
+	} else {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-events:
+			debounce = time.After(debounceDelay)
+		case err := <-errs:
+			// The notifier stopped, so files are polled instead.
+			w.setErr(err)
+			events, errs = nil, nil
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			tick = ticker.C
+			w.check()
+		case <-debounce:
+			debounce = nil
+			w.check()
+		case <-tick:
+			w.check()
+		}
+	}
+}
+
+// readFiles reads files and returns true if any of them changed.
+// Files which can't be read, for example because they're being
+// replaced, are checked again on the next event.
+func (w *Watcher) readFiles() bool {
+	changed := false
+	for _, name := range w.files {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			continue
+		}
+		if old, ok := w.contents[name]; !ok || !bytes.Equal(old, data) {
+			w.contents[name] = data
+			changed = true
+		}
+	}
+	return changed
+}
+
+func (w *Watcher) check() {
+	if w.readFiles() {
+		w.reload()
+	}
+}
+
+func (w *Watcher) reload() {
+	target := reflect.New(w.defaults.Type())
+	p, err := w.load(target.Elem())
+	w.setErr(err)
+	if err != nil {
+		return
+	}
+
+	old := w.current.Load()
+	fields := diffFields(reflect.ValueOf(old).Elem(), target.Elem(), p)
+	w.current.Store(target.Interface())
+	if len(fields) == 0 {
+		return
+	}
+
+	w.mu.Lock()
+	subscribers := append([]func(Change){}, w.subscribers...)
+	w.mu.Unlock()
+
+	change := Change{Old: old, New: target.Interface(), Fields: fields}
+	for _, fn := range subscribers {
+		fn(change)
+	}
+}
+
+// load loads `v` from a copy of defaults, so configs don't share
+// pointers, slices or maps.
+func (w *Watcher) load(v reflect.Value) (Provenance, error) {
+	p := Provenance{}
+	opts := append(w.opts[:len(w.opts):len(w.opts)],
+		WithDefaults(deepCopy(w.defaults).Interface()),
+		WithProvenance(p))
+
+	return p, Load(v.Addr().Interface(), opts...)
+}
+
+func (w *Watcher) setErr(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.err = err
+}
+
+// diffFields returns fields of `new` which differ from `old`.
+func diffFields(old, new reflect.Value, p Provenance) []FieldChange {
+	oldFields := make(map[string]fieldInfo)
+	walkFields(old, DefaultEnvSeparator, func(f fieldInfo) {
+		oldFields[f.Path] = f
+	})
+
+	var changes []FieldChange
+	walkFields(new, DefaultEnvSeparator, func(f fieldInfo) {
+		o := oldFields[f.Path]
+		if rawField(o) == rawField(f) {
+			return
+		}
+		changes = append(changes, FieldChange{
+			Field:  f.Path,
+			Old:    safeValue(o, formatField(o)),
+			New:    safeValue(f, formatField(f)),
+			Source: p[f.Path],
+		})
+	})
+	return changes
+}
+
+// deepCopy copies `v` with values of pointers, slices and maps
+// in exported fields.
+func deepCopy(v reflect.Value) reflect.Value {
+	c := reflect.New(v.Type()).Elem()
+	copyValue(c, v)
+	return c
+}
+
+func copyValue(dst, src reflect.Value) {
+	switch src.Kind() {
+	case reflect.Ptr:
+		if src.IsNil() {
+			return
+		}
+		p := reflect.New(src.Type().Elem())
+		copyValue(p.Elem(), src.Elem())
+		dst.Set(p)
+	case reflect.Struct:
+		dst.Set(src)
+		for i := 0; i < dst.NumField(); i++ {
+			if dst.Field(i).CanSet() {
+				copyValue(dst.Field(i), src.Field(i))
+			}
+		}
+	case reflect.Slice:
+		if src.IsNil() {
+			return
+		}
+		s := reflect.MakeSlice(src.Type(), src.Len(), src.Len())
+		for i := 0; i < src.Len(); i++ {
+			copyValue(s.Index(i), src.Index(i))
+		}
+		dst.Set(s)
+	case reflect.Map:
+		if src.IsNil() {
+			return
+		}
+		m := reflect.MakeMap(src.Type())
+		for _, key := range src.MapKeys() {
+			elem := reflect.New(src.Type().Elem()).Elem()
+			copyValue(elem, src.MapIndex(key))
+			m.SetMapIndex(key, elem)
+		}
+		dst.Set(m)
+	default:
+		dst.Set(src)
+	}
+}
@@ -0,0 +1,151 @@
+package config
+
+import (
+	"os"
+	"sync"
+	"syscall"
+)
+
+// inotifyMask selects files created, written, removed and renamed
+// in a directory.
+const inotifyMask = syscall.IN_CREATE | syscall.IN_CLOSE_WRITE | syscall.IN_MODIFY |
+	syscall.IN_ATTRIB | syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO
+
+// dirNotifier sends to `events` when files in watched directories
+// change. Events aren't parsed, as the watcher reads all its files
+// after any of them.
+type dirNotifier struct {
+	events chan struct{}
+	errs   chan error
+
+	fd      int
+	epfd    int
+	wake    [2]int
+	stopped chan struct{}
+
+	// mu guards descriptors, which are closed once by `closeFds()`,
+	// so `Close()` doesn't write to a reused descriptor after `run()`
+	// stops on an error.
+	mu     sync.Mutex
+	closed bool
+}
+
+// newDirNotifier watches `dirs` with inotify. The inotify descriptor
+// is non-blocking and waited for with epoll, together with a pipe
+// which wakes it up on `Close()`.
+func newDirNotifier(dirs []string) (*dirNotifier, error) {
+	n := &dirNotifier{
+		events:  make(chan struct{}, 1),
+		errs:    make(chan error, 1),
+		fd:      -1,
+		epfd:    -1,
+		wake:    [2]int{-1, -1},
+		stopped: make(chan struct{}),
+	}
+	fail := func(name string, err error) (*dirNotifier, error) {
+		n.closeFds()
+		return nil, os.NewSyscallError(name, err)
+	}
+
+	var err error
+	if n.fd, err = syscall.InotifyInit1(syscall.IN_NONBLOCK | syscall.IN_CLOEXEC); err != nil {
+		return fail("inotify_init1", err)
+	}
+	for _, dir := range dirs {
+		if _, err := syscall.InotifyAddWatch(n.fd, dir, inotifyMask); err != nil {
+			return fail("inotify_add_watch", err)
+		}
+	}
+
+	if n.epfd, err = syscall.EpollCreate1(syscall.EPOLL_CLOEXEC); err != nil {
+		return fail("epoll_create1", err)
+	}
+	if err := syscall.Pipe2(n.wake[:], syscall.O_NONBLOCK|syscall.O_CLOEXEC); err != nil {
+		return fail("pipe2", err)
+	}
+	for _, fd := range []int{n.fd, n.wake[0]} {
+		event := syscall.EpollEvent{Events: syscall.EPOLLIN, Fd: int32(fd)}
+		if err := syscall.EpollCtl(n.epfd, syscall.EPOLL_CTL_ADD, fd, &event); err != nil {
+			return fail("epoll_ctl", err)
+		}
+	}
+
+	go n.run()
+	return n, nil
+}
+
+// Close stops the notifier and closes its descriptors. Calling it
+// again or after the notifier stopped on an error does nothing.
+func (n *dirNotifier) Close() error {
+	n.mu.Lock()
+	if !n.closed {
+		syscall.Write(n.wake[1], []byte{0})
+	}
+	n.mu.Unlock()
+
+	<-n.stopped
+	return nil
+}
+
+func (n *dirNotifier) run() {
+	defer close(n.stopped)
+	defer n.closeFds()
+
+	events := make([]syscall.EpollEvent, 2)
+	buf := make([]byte, 4096)
+	for {
+		count, err := syscall.EpollWait(n.epfd, events, -1)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			n.sendErr(os.NewSyscallError("epoll_wait", err))
+			return
+		}
+		for _, e := range events[:count] {
+			if int(e.Fd) == n.wake[0] {
+				return
+			}
+		}
+
+		// Only the fact that something changed matters, so queued
+		// events are dropped.
+		for {
+			_, err := syscall.Read(n.fd, buf)
+			if err == syscall.EAGAIN {
+				break
+			}
+			if err != nil && err != syscall.EINTR {
+				n.sendErr(os.NewSyscallError("read", err))
+				return
+			}
+		}
+
+		select {
+		case n.events <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (n *dirNotifier) sendErr(err error) {
+	select {
+	case n.errs <- err:
+	default:
+	}
+}
+
+func (n *dirNotifier) closeFds() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.closed {
+		return
+	}
+	n.closed = true
+	for _, fd := range []int{n.fd, n.epfd, n.wake[0], n.wake[1]} {
+		if fd >= 0 {
+			syscall.Close(fd)
+		}
+	}
+}
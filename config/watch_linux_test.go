@@ -0,0 +1,38 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDirNotifier(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	n, err := newDirNotifier([]string{dir})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "config.json"), []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-n.events:
+	case err := <-n.errs:
+		t.Error(err)
+	case <-time.After(5 * time.Second):
+		t.Error("timeout waiting for an event")
+	}
+
+	assert.NoError(t, n.Close())
+	assert.NoError(t, n.Close(), "closing again does nothing")
+
+	_, err = newDirNotifier([]string{filepath.Join(dir, "missing")})
+	assert.Error(t, err)
+}
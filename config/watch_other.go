@@ -0,0 +1,21 @@
+//go:build !linux
+// +build !linux
+
+package config
+
+import "errors"
+
+// dirNotifier isn't available outside of Linux, so files are polled.
+type dirNotifier struct {
+	events chan struct{}
+	errs   chan error
+}
+
+func newDirNotifier(dirs []string) (*dirNotifier, error) {
+	return nil, errors.New("config: inotify is only available on linux")
+}
+
+// Close does nothing.
+func (n *dirNotifier) Close() error {
+	return nil
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type watchedConfig struct {
+	*Config
+	Port     int      `json:"port" validate:"min=1"`
+	Password Secret   `json:"password"`
+	Hosts    []string `json:"hosts"`
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// replaceFile writes a temporary file and renames it, like editors do.
+func replaceFile(t *testing.T, filename, content string) {
+	tmp := filename + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, filename); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func waitChange(t *testing.T, changes <-chan Change) (Change, bool) {
+	select {
+	case change := <-changes:
+		return change, true
+	case <-time.After(5 * time.Second):
+		t.Error("timeout waiting for a change")
+		return Change{}, false
+	}
+}
+
+// waitErr waits until the last reload of `w` fails.
+func waitErr(t *testing.T, w *Watcher) error {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if err := w.Err(); err != nil {
+			return err
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("timeout waiting for an error")
+	return nil
+}
+
+func testWatch(t *testing.T, opts ...Option) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "config.json")
+	replaceFile(t, filename, `{"port":80,"password":"a","hosts":["a"]}`)
+
+	c := &watchedConfig{}
+	w, err := Watch(c, append(opts, WithFile(filename), WithEnv(false))...)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer w.Close()
+	assert.Equal(t, 80, c.Port)
+	assert.Equal(t, filename, c.ConfigFilePath)
+	assert.True(t, c == w.Current())
+
+	changes := make(chan Change, 10)
+	w.Subscribe(func(change Change) {
+		changes <- change
+	})
+
+	replaceFile(t, filename, `{"port":8080,"password":"b","hosts":["a"],"debug":true}`)
+	change, ok := waitChange(t, changes)
+	if !ok {
+		return
+	}
+	assert.True(t, change.Old == c)
+	assert.True(t, change.New == w.Current())
+	assert.Equal(t, []FieldChange{
+		{Field: "debug", Old: "false", New: "true", Source: Source{Kind: SourceFile, Name: filename}},
+		{Field: "port", Old: "80", New: "8080", Source: Source{Kind: SourceFile, Name: filename}},
+		{Field: "password", Old: Redacted, New: Redacted, Source: Source{Kind: SourceFile, Name: filename}},
+	}, change.Fields)
+	assert.Equal(t, 80, c.Port, "target isn't changed")
+	assert.NoError(t, w.Err())
+
+	current := w.Current().(*watchedConfig)
+	current.Hosts[0] = "changed"
+	assert.Equal(t, []string{"a"}, c.Hosts, "configs don't share slices")
+
+	replaceFile(t, filename, `{"port":0}`)
+	assert.Error(t, waitErr(t, w))
+	assert.True(t, current == w.Current(), "invalid config is rejected")
+
+	replaceFile(t, filename, `{"port":9000}`)
+	if change, ok := waitChange(t, changes); ok {
+		assert.Equal(t, 9000, change.New.(*watchedConfig).Port)
+		assert.NoError(t, w.Err())
+	}
+
+	assert.NoError(t, w.Close())
+	assert.NoError(t, w.Close(), "closing again does nothing")
+}
+
+func TestWatch(t *testing.T) {
+	testWatch(t)
+}
+
+func TestWatchPolling(t *testing.T) {
+	testWatch(t, WithPolling(10*time.Millisecond))
+}
+
+func TestWatchSymlinkSwap(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	// Kubernetes mounts config maps as symlinks to a `..data` symlink,
+	// which is replaced with a link to a new directory on updates.
+	link := func(version, content string) {
+		versionDir := filepath.Join(dir, version)
+		if err := os.Mkdir(versionDir, 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(versionDir, "config.json"), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		tmp := filepath.Join(dir, "..data_tmp")
+		if err := os.Symlink(version, tmp); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(tmp, filepath.Join(dir, "..data")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	link("..v1", `{"port":80}`)
+	filename := filepath.Join(dir, "config.json")
+	if err := os.Symlink(filepath.Join("..data", "config.json"), filename); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := Watch(&watchedConfig{}, WithFile(filename), WithEnv(false))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer w.Close()
+
+	changes := make(chan Change, 10)
+	w.Subscribe(func(change Change) {
+		changes <- change
+	})
+
+	link("..v2", `{"port":8080}`)
+	if change, ok := waitChange(t, changes); ok {
+		assert.Equal(t, 8080, change.New.(*watchedConfig).Port)
+	}
+}
+
+func TestWatchErrors(t *testing.T) {
+	_, err := Watch(&watchedConfig{})
+	assert.EqualError(t, err, "config: no files to watch")
+
+	filename := writeTempFile(t, `{"port":0}`)
+	defer os.Remove(filename)
+	_, err = Watch(&watchedConfig{}, WithFile(filename), WithEnv(false))
+	assert.Error(t, err)
+}
@@ -0,0 +1,405 @@
+package dockertest
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+	"time"
+
+	dc "github.com/fsouza/go-dockerclient"
+)
+
+type (
+	// WaitFunc checks if a started container is ready.
+	// It's called repeatedly until it returns nil or the timeout passes.
+	WaitFunc func(r *Resource) error
+
+	// ContainerBuilder builds and runs a container.
+	// Use `NewContainer()` to create one.
+	ContainerBuilder struct {
+		opts        dc.CreateContainerOptions
+		mounts      Mounts
+		ports       []PortMapping
+		files       []fileCopy
+		networks    []networkAttachment
+		pullImage   bool
+		wait        WaitFunc
+		waitTimeout time.Duration
+		database    *Database
+		init        []initStep
+	}
+
+	networkAttachment struct {
+		name    string
+		aliases []string
+	}
+
+	fileCopy struct {
+		source string
+		target string
+	}
+
+	// Resource is a handle to a container started by `ContainerBuilder`.
+	Resource struct {
+		Container *dc.Container
+
+		pool    *Pool
+		builder *ContainerBuilder
+	}
+)
+
+// NewContainer returns a builder of a container with the given image.
+// Missing images are pulled by default.
+func NewContainer(image string) *ContainerBuilder {
+	return &ContainerBuilder{
+		opts: dc.CreateContainerOptions{
+			Config:     &dc.Config{Image: image},
+			HostConfig: &dc.HostConfig{},
+		},
+		pullImage: true,
+	}
+}
+
+// WithName sets a container name.
+func (b *ContainerBuilder) WithName(name string) *ContainerBuilder {
+	b.opts.Name = name
+	return b
+}
+
+// WithEnv adds env variables in format NAME=VALUE.
+func (b *ContainerBuilder) WithEnv(env ...string) *ContainerBuilder {
+	b.opts.Config.Env = append(b.opts.Config.Env, env...)
+	return b
+}
+
+// WithCmd sets a command.
+func (b *ContainerBuilder) WithCmd(cmd ...string) *ContainerBuilder {
+	b.opts.Config.Cmd = cmd
+	return b
+}
+
+// WithLabel adds a label.
+func (b *ContainerBuilder) WithLabel(key, value string) *ContainerBuilder {
+	if b.opts.Config.Labels == nil {
+		b.opts.Config.Labels = make(map[string]string)
+	}
+	b.opts.Config.Labels[key] = value
+	return b
+}
+
+// WithPort exposes ports, for example "5432/tcp", and publishes them
+// on random host ports. Ports without a protocol use TCP.
+func (b *ContainerBuilder) WithPort(ports ...string) *ContainerBuilder {
+	if b.opts.Config.ExposedPorts == nil {
+		b.opts.Config.ExposedPorts = make(map[dc.Port]struct{})
+	}
+	for _, port := range ports {
+		b.opts.Config.ExposedPorts[normalizePort(port)] = struct{}{}
+	}
+	b.opts.HostConfig.PublishAllPorts = true
+	return b
+}
+
+// WithPortBinding publishes ports on fixed host ports or interfaces.
+func (b *ContainerBuilder) WithPortBinding(ports ...PortMapping) *ContainerBuilder {
+	b.ports = append(b.ports, ports...)
+	return b
+}
+
+// WithMount adds mounts.
+func (b *ContainerBuilder) WithMount(mounts ...Mount) *ContainerBuilder {
+	b.mounts = append(b.mounts, mounts...)
+	return b
+}
+
+// WithFile copies a host file to `target` in the container before
+// it's started. It's useful for init scripts and config files.
+func (b *ContainerBuilder) WithFile(source, target string) *ContainerBuilder {
+	b.files = append(b.files, fileCopy{source, target})
+	return b
+}
+
+// WithNetwork connects the container to a network with optional aliases.
+func (b *ContainerBuilder) WithNetwork(name string, aliases ...string) *ContainerBuilder {
+	b.networks = append(b.networks, networkAttachment{name, aliases})
+	return b
+}
+
+// WithPull sets whether a missing image should be pulled.
+func (b *ContainerBuilder) WithPull(pullImage bool) *ContainerBuilder {
+	b.pullImage = pullImage
+	return b
+}
+
+// WithWait sets a readiness check which is retried up to `timeout`
+// after the container starts.
+func (b *ContainerBuilder) WithWait(timeout time.Duration, wait WaitFunc) *ContainerBuilder {
+	b.wait = wait
+	b.waitTimeout = timeout
+	return b
+}
+
+// Options returns container options built so far.
+func (b *ContainerBuilder) Options() dc.CreateContainerOptions {
+	opts := b.opts
+	hostConfig := *b.opts.HostConfig
+	hostConfig.Binds = append([]string(nil), hostConfig.Binds...)
+	b.mounts.Apply(&hostConfig)
+	opts.HostConfig = &hostConfig
+
+	if len(b.ports) > 0 {
+		config := *b.opts.Config
+		config.ExposedPorts = make(map[dc.Port]struct{})
+		for port := range b.opts.Config.ExposedPorts {
+			config.ExposedPorts[port] = struct{}{}
+		}
+		opts.Config = &config
+
+		hostConfig.PortBindings = make(map[dc.Port][]dc.PortBinding)
+		for port, bindings := range b.opts.HostConfig.PortBindings {
+			hostConfig.PortBindings[port] = append([]dc.PortBinding(nil), bindings...)
+		}
+		for _, m := range b.ports {
+			m.apply(&opts)
+		}
+	}
+
+	if len(b.networks) > 0 {
+		first := b.networks[0]
+		opts.NetworkingConfig = &dc.NetworkingConfig{
+			EndpointsConfig: map[string]*dc.EndpointConfig{
+				first.name: {Aliases: first.aliases},
+			},
+		}
+	}
+
+	return opts
+}
+
+// Run runs the container in the pool and waits until it's ready.
+// If a database is set, it also waits for it and runs init scripts.
+// If any of these steps fails, the container is purged.
+func (b *ContainerBuilder) Run(pool *Pool) (*Resource, error) {
+	if len(b.init) > 0 && b.database == nil {
+		return nil, errors.New("dockertest: init scripts require WithDatabase()")
+	}
+
+	if err := pool.ensureImage(b.opts.Config.Image, b.pullImage); err != nil {
+		return nil, err
+	}
+
+	container, err := pool.createContainer(b.Options())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b.files) > 0 {
+		if err := uploadFiles(pool.Client, container.ID, b.files); err != nil {
+			pool.Client.RemoveContainer(dc.RemoveContainerOptions{
+				ID:    container.ID,
+				Force: true,
+			})
+			return nil, err
+		}
+	}
+
+	container, err = pool.startContainer(container)
+	if err != nil {
+		return nil, err
+	}
+	r := &Resource{Container: container, pool: pool, builder: b}
+
+	if len(b.networks) > 1 {
+		for _, n := range b.networks[1:] {
+			if err := pool.Client.ConnectNetwork(n.name, dc.NetworkConnectionOptions{
+				Container:      container.ID,
+				EndpointConfig: &dc.EndpointConfig{Aliases: n.aliases},
+			}); err != nil {
+				r.Purge()
+				return nil, err
+			}
+		}
+
+		if r.Container, err = pool.Client.InspectContainer(container.ID); err != nil {
+			r.Purge()
+			return nil, err
+		}
+		pool.updateContainer(r.Container)
+	}
+
+	if err := b.waitReady(pool, r, true); err != nil {
+		r.Purge()
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// waitReady runs the readiness check and database init scripts.
+// Init scripts are skipped if `runInit` is false.
+func (b *ContainerBuilder) waitReady(pool *Pool, r *Resource, runInit bool) error {
+	if b.wait == nil && b.database == nil {
+		return nil
+	}
+
+	op := &Operation{Event: EventReady, Image: b.opts.Config.Image, Container: r.Container}
+	if err := pool.before(op); err != nil {
+		return err
+	}
+
+	var err error
+	if b.wait != nil {
+		err = Retry(b.waitTimeout, func() error { return b.wait(r) })
+	}
+	if err == nil && b.database != nil {
+		err = b.initDatabase(r, runInit)
+	}
+
+	return pool.after(op, err)
+}
+
+// uploadFiles copies host files into the container as a tar archive.
+func uploadFiles(client *dc.Client, id string, files []fileCopy) error {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+
+	for _, f := range files {
+		data, err := ioutil.ReadFile(f.source)
+		if err != nil {
+			return err
+		}
+
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    strings.TrimPrefix(f.target, "/"),
+			Mode:    0644,
+			Size:    int64(len(data)),
+			ModTime: time.Now(),
+		}); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	return client.UploadToContainer(id, dc.UploadToContainerOptions{
+		InputStream: &buf,
+		Path:        "/",
+	})
+}
+
+// WaitForPort returns a `WaitFunc` which succeeds when a TCP connection
+// to the published `port` can be established.
+func WaitForPort(port string) WaitFunc {
+	return func(r *Resource) error {
+		conn, err := net.DialTimeout("tcp", r.Addr(port), time.Second)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+// WaitForExec returns a `WaitFunc` which succeeds when `cmd` executed
+// in the container exits with 0.
+func WaitForExec(cmd ...string) WaitFunc {
+	return func(r *Resource) error {
+		res, err := r.Exec(cmd...)
+		if err != nil {
+			return err
+		}
+		if res.ExitCode != 0 {
+			return fmt.Errorf("%s exited with %d", strings.Join(cmd, " "), res.ExitCode)
+		}
+		return nil
+	}
+}
+
+// WaitForLog returns a `WaitFunc` which succeeds when container logs
+// contain `text`.
+func WaitForLog(text string) WaitFunc {
+	return func(r *Resource) error {
+		logs, err := r.Logs()
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(logs, text) {
+			return fmt.Errorf("%q not found in logs", text)
+		}
+		return nil
+	}
+}
+
+// ID returns the container ID.
+func (r *Resource) ID() string {
+	return r.Container.ID
+}
+
+// Addr returns a local host:port address of the published `port`,
+// for example "5432/tcp" or "53/udp".
+func (r *Resource) Addr(port string) string {
+	return GetAddr(r.Container, port, "")
+}
+
+// URL returns an address of the published `port` with the scheme,
+// for example "http://127.0.0.1:32768".
+func (r *Resource) URL(scheme, port string) string {
+	return GetAddr(r.Container, port, scheme)
+}
+
+// Logs returns stdout and stderr of the container.
+func (r *Resource) Logs() (string, error) {
+	var buf bytes.Buffer
+	err := r.pool.Logs(&buf, r.Container.ID, "all", false)
+	return buf.String(), err
+}
+
+// Exec runs a command in the container and returns its exit code and output.
+func (r *Resource) Exec(cmd ...string) (*JobResult, error) {
+	exec, err := r.pool.Client.CreateExec(dc.CreateExecOptions{
+		Container:    r.Container.ID,
+		Cmd:          cmd,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := r.pool.Client.StartExec(exec.ID, dc.StartExecOptions{
+		OutputStream: &stdout,
+		ErrorStream:  &stderr,
+	}); err != nil {
+		return nil, err
+	}
+
+	inspect, err := r.pool.Client.InspectExec(exec.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &JobResult{
+		ExitCode: inspect.ExitCode,
+		Stdout:   stdout.Bytes(),
+		Stderr:   stderr.Bytes(),
+	}, nil
+}
+
+// Purge removes the container from the docker and the pool.
+func (r *Resource) Purge() error {
+	return r.pool.PurgeContainer(r.Container)
+}
+
+// String returns a container name or a short ID.
+func (r *Resource) String() string {
+	return containerName(r.Container)
+}
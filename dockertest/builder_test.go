@@ -0,0 +1,84 @@
+package dockertest
+
+import (
+	"testing"
+	"time"
+
+	dc "github.com/fsouza/go-dockerclient"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestContainerBuilderOptions(t *testing.T) {
+	Convey("Given a container builder", t, func() {
+		b := NewContainer("postgres:9.6").
+			WithName("db").
+			WithEnv("POSTGRES_PASSWORD=secret").
+			WithPort("5432/tcp").
+			WithCmd("postgres", "-c", "fsync=off").
+			WithLabel("app", "test").
+			WithMount(TmpfsMount("/var/lib/postgresql/data", "")).
+			WithNetwork("test-net", "db", "postgres")
+
+		Convey("Should build container options", func() {
+			opts := b.Options()
+			So(opts.Name, ShouldEqual, "db")
+			So(opts.Config.Image, ShouldEqual, "postgres:9.6")
+			So(opts.Config.Env, ShouldResemble, []string{"POSTGRES_PASSWORD=secret"})
+			So(opts.Config.Cmd, ShouldResemble, []string{"postgres", "-c", "fsync=off"})
+			So(opts.Config.Labels["app"], ShouldEqual, "test")
+			So(opts.Config.ExposedPorts, ShouldContainKey, dc.Port("5432/tcp"))
+			So(opts.HostConfig.PublishAllPorts, ShouldBeTrue)
+			So(opts.HostConfig.Tmpfs, ShouldContainKey, "/var/lib/postgresql/data")
+			So(opts.NetworkingConfig.EndpointsConfig["test-net"].Aliases,
+				ShouldResemble, []string{"db", "postgres"})
+		})
+
+		Convey("Should default ports to TCP", func() {
+			opts := b.WithPort("6379").Options()
+			So(opts.Config.ExposedPorts, ShouldContainKey, dc.Port("6379/tcp"))
+			So(opts.Config.ExposedPorts, ShouldNotContainKey, dc.Port("6379"))
+		})
+
+		Convey("Should not accumulate mounts between calls", func() {
+			b.WithMount(BindMount("/tmp", "/tmp"))
+			b.Options()
+			So(b.Options().HostConfig.Binds, ShouldHaveLength, 1)
+		})
+	})
+}
+
+func TestContainerBuilderRun(t *testing.T) {
+	Convey("Given a new pool", t, func() {
+		pool, err := NewPool("")
+		So(err, ShouldBeNil)
+
+		Convey("When running a container with a builder", func() {
+			r, err := NewContainer(testLocalImage).
+				WithPull(false).
+				WithPort("8888/tcp").
+				WithWait(10*time.Second, WaitForPort("8888/tcp")).
+				Run(pool)
+			So(err, ShouldBeNil)
+
+			Convey("Should return its address", func() {
+				So(r.Addr("8888/tcp"), ShouldStartWith, "127.0.0.1:")
+			})
+
+			Convey("Should exec commands", func() {
+				res, err := r.Exec("echo", "hello")
+				So(err, ShouldBeNil)
+				So(res.ExitCode, ShouldEqual, 0)
+				So(string(res.Stdout), ShouldEqual, "hello\n")
+			})
+
+			Convey("Should purge it", func() {
+				So(r.Purge(), ShouldBeNil)
+				So(len(pool.Containers), ShouldEqual, 0)
+			})
+		})
+
+		Reset(func() {
+			pool.PurgeAll()
+		})
+	})
+}
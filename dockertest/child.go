@@ -0,0 +1,257 @@
+package dockertest
+
+import (
+	"fmt"
+	"strings"
+
+	dc "github.com/fsouza/go-dockerclient"
+)
+
+// LabelPool is a label with a name of the sub-pool which created
+// the resource. It's not set for resources of a root pool.
+const LabelPool = "dockertest.pool"
+
+// Child creates a sub-pool with its own network, for example for
+// a parallel test. It shares the client, session, hooks and report
+// of the parent pool. The sub-pool:
+//
+//   - prefixes names of containers, networks and volumes with `name`,
+//     including sources of volume mounts,
+//   - connects containers without network settings to its network,
+//     where they are reachable by their names without the prefix,
+//   - finds containers of parent pools with `GetContainer()`,
+//   - purges only its own resources with `PurgeAll()`.
+//
+// `name` can be a test name; characters not allowed in docker names
+// are replaced with `_`. Names of sub-pools of a pool must be unique.
+// Sub-pools are purged by `PurgeAll()` of the parent pool; a purged
+// sub-pool is removed from its parent, so its name can be reused.
+func (p *Pool) Child(name string) (*Pool, error) {
+	child := p.newChild(name)
+
+	// The name is reserved before the network is created, so sub-pools
+	// created concurrently can't get the same prefix.
+	p.rw.Lock()
+	for _, c := range p.children {
+		if c.name == child.name {
+			p.rw.Unlock()
+			return nil, fmt.Errorf("dockertest: sub-pool %s already exists", child.name)
+		}
+	}
+	p.children = append(p.children, child)
+	p.rw.Unlock()
+
+	net, err := child.CreateNetwork(p.Session)
+	if err != nil {
+		p.removeChild(child)
+		return nil, err
+	}
+	child.network = net.Name
+
+	return child, nil
+}
+
+// removeChild removes a sub-pool from the pool. The slice is copied,
+// so callers iterating over the old one aren't affected.
+func (p *Pool) removeChild(child *Pool) {
+	p.rw.Lock()
+	defer p.rw.Unlock()
+
+	children := make([]*Pool, 0, len(p.children))
+	for _, c := range p.children {
+		if c != child {
+			children = append(children, c)
+		}
+	}
+	p.children = children
+}
+
+// newChild returns a sub-pool without a network.
+func (p *Pool) newChild(name string) *Pool {
+	return &Pool{
+		Client:  p.Client,
+		Session: p.Session,
+		name:    p.scopedName(sanitizeName(name)),
+		parent:  p,
+	}
+}
+
+// Name returns a name of the sub-pool or an empty string for
+// a root pool.
+func (p *Pool) Name() string {
+	return p.name
+}
+
+// Network returns a name of the sub-pool network or an empty string
+// for a root pool.
+func (p *Pool) Network() string {
+	return p.network
+}
+
+// scopedName prefixes `name` with the sub-pool name.
+func (p *Pool) scopedName(name string) string {
+	if p.name == "" {
+		return name
+	}
+	return p.name + "_" + name
+}
+
+// hasScope returns true if `name` already has the sub-pool prefix.
+func (p *Pool) hasScope(name string) bool {
+	return p.name != "" && strings.HasPrefix(name, p.name+"_")
+}
+
+// root returns the root pool of a sub-pool.
+func (p *Pool) root() *Pool {
+	for p.parent != nil {
+		p = p.parent
+	}
+	return p
+}
+
+// allContainers returns containers tracked by the pool and
+// its sub-pools.
+func (p *Pool) allContainers() ContainerList {
+	p.rw.RLock()
+	containers := append(ContainerList(nil), p.Containers...)
+	children := p.children
+	p.rw.RUnlock()
+
+	for _, child := range children {
+		containers = append(containers, child.allContainers()...)
+	}
+	return containers
+}
+
+// hasVolume returns true if the pool tracks a volume named `name`.
+func (p *Pool) hasVolume(name string) bool {
+	p.rw.RLock()
+	defer p.rw.RUnlock()
+
+	for _, vol := range p.Volumes {
+		if vol.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// scopedVolume returns a name of the volume mounted as `name`. Like
+// `GetContainer()`, it prefers volumes of the sub-pool and then of
+// parent pools. Volumes tracked under their own name, like anonymous
+// volumes kept by `Recreate()`, are used as they are. Other volumes
+// get the sub-pool prefix, so volumes created by the docker on mount
+// aren't shared between sub-pools.
+func (p *Pool) scopedVolume(name string) string {
+	if p.name == "" || p.hasScope(name) || p.hasVolume(name) {
+		return name
+	}
+
+	for pool := p; pool != nil; pool = pool.parent {
+		if scoped := pool.scopedName(name); pool.hasVolume(scoped) {
+			return scoped
+		}
+	}
+	return p.scopedName(name)
+}
+
+// scopeBinds prefixes named volumes in binds in format
+// SOURCE:TARGET[:MODE]. Host paths are kept.
+func (p *Pool) scopeBinds(binds []string) []string {
+	if binds == nil {
+		return nil
+	}
+
+	scoped := make([]string, len(binds))
+	for i, bind := range binds {
+		parts := strings.SplitN(bind, ":", 2)
+		if len(parts) == 2 && isVolumeName(parts[0]) {
+			bind = p.scopedVolume(parts[0]) + ":" + parts[1]
+		}
+		scoped[i] = bind
+	}
+	return scoped
+}
+
+// scopeMounts prefixes sources of volume mounts.
+func (p *Pool) scopeMounts(mounts []dc.HostMount) []dc.HostMount {
+	if mounts == nil {
+		return nil
+	}
+
+	scoped := make([]dc.HostMount, len(mounts))
+	for i, m := range mounts {
+		if m.Type == MountVolume && m.Source != "" {
+			m.Source = p.scopedVolume(m.Source)
+		}
+		scoped[i] = m
+	}
+	return scoped
+}
+
+// isVolumeName returns true if `source` of a bind is a named volume
+// rather than a host path.
+func isVolumeName(source string) bool {
+	if source == "" || !isAlphanumeric(source[0]) {
+		return false
+	}
+	return sanitizeName(source) == source
+}
+
+func isAlphanumeric(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// scopeOptions prefixes the container name and sources of volume
+// mounts and connects the container to the sub-pool network unless
+// other network settings are given. Names which already have
+// the prefix, for example of recreated containers, are kept.
+func (p *Pool) scopeOptions(opts *dc.CreateContainerOptions) {
+	if p.name == "" {
+		return
+	}
+
+	var aliases []string
+	if opts.Name != "" && !p.hasScope(opts.Name) {
+		aliases = []string{opts.Name}
+		opts.Name = p.scopedName(opts.Name)
+	}
+
+	if opts.HostConfig != nil && (len(opts.HostConfig.Binds) > 0 || len(opts.HostConfig.Mounts) > 0) {
+		hostConfig := *opts.HostConfig
+		hostConfig.Binds = p.scopeBinds(hostConfig.Binds)
+		hostConfig.Mounts = p.scopeMounts(hostConfig.Mounts)
+		opts.HostConfig = &hostConfig
+	}
+
+	if p.network == "" || opts.NetworkingConfig != nil ||
+		opts.HostConfig != nil && opts.HostConfig.NetworkMode != "" {
+		return
+	}
+
+	hostConfig := dc.HostConfig{}
+	if opts.HostConfig != nil {
+		hostConfig = *opts.HostConfig
+	}
+	hostConfig.NetworkMode = p.network
+	opts.HostConfig = &hostConfig
+	opts.NetworkingConfig = &dc.NetworkingConfig{
+		EndpointsConfig: map[string]*dc.EndpointConfig{
+			p.network: {Aliases: aliases},
+		},
+	}
+}
+
+// sanitizeName replaces characters not allowed in docker names,
+// for example `/` in names of subtests.
+func sanitizeName(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		switch {
+		case isAlphanumeric(c), c == '_', c == '.', c == '-':
+		default:
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,178 @@
+package dockertest
+
+import (
+	"testing"
+
+	dc "github.com/fsouza/go-dockerclient"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestChildScope(t *testing.T) {
+	Convey("Given a sub-pool of a subtest", t, func() {
+		parent := &Pool{Session: "abc"}
+		child := parent.newChild("TestApi/get user")
+		child.network = child.scopedName("abc")
+
+		Convey("Should sanitize and prefix names", func() {
+			So(child.Name(), ShouldEqual, "TestApi_get_user")
+			So(child.Network(), ShouldEqual, "TestApi_get_user_abc")
+			So(child.newChild("db").Name(), ShouldEqual, "TestApi_get_user_db")
+			So(child.labels(nil)[LabelPool], ShouldEqual, "TestApi_get_user")
+			So(parent.labels(nil), ShouldNotContainKey, LabelPool)
+		})
+
+		Convey("Should connect named containers to its network", func() {
+			opts := dc.CreateContainerOptions{Name: "db", Config: &dc.Config{}}
+			child.scopeOptions(&opts)
+
+			So(opts.Name, ShouldEqual, "TestApi_get_user_db")
+			So(opts.HostConfig.NetworkMode, ShouldEqual, "TestApi_get_user_abc")
+			So(opts.NetworkingConfig.EndpointsConfig["TestApi_get_user_abc"].Aliases,
+				ShouldResemble, []string{"db"})
+		})
+
+		Convey("Should keep explicit network settings", func() {
+			hostConfig := &dc.HostConfig{NetworkMode: "host"}
+			opts := dc.CreateContainerOptions{HostConfig: hostConfig}
+			child.scopeOptions(&opts)
+
+			So(opts.HostConfig, ShouldEqual, hostConfig)
+			So(opts.NetworkingConfig, ShouldBeNil)
+		})
+
+		Convey("Should prefix named volumes of mounts", func() {
+			parent.Volumes = []*dc.Volume{{Name: "shared"}}
+			hostConfig := &dc.HostConfig{}
+			Mounts{
+				VolumeMount("data", "/data"),
+				VolumeMount("shared", "/shared"),
+				VolumeMount("TestApi_get_user_cache", "/cache"),
+				BindMount("/tmp/data", "/tmp/data"),
+			}.Apply(hostConfig)
+			hostConfig.Mounts = []dc.HostMount{
+				{Type: MountVolume, Source: "logs", Target: "/logs"},
+				{Type: MountBind, Source: "logs", Target: "/var/log"},
+			}
+
+			opts := dc.CreateContainerOptions{HostConfig: hostConfig}
+			child.scopeOptions(&opts)
+
+			So(opts.HostConfig.Binds, ShouldResemble, []string{
+				"TestApi_get_user_data:/data",
+				"shared:/shared",
+				"TestApi_get_user_cache:/cache",
+				"/tmp/data:/tmp/data",
+			})
+			So(opts.HostConfig.Mounts[0].Source, ShouldEqual, "TestApi_get_user_logs")
+			So(opts.HostConfig.Mounts[1].Source, ShouldEqual, "logs")
+			So(hostConfig.Binds[0], ShouldEqual, "data:/data")
+		})
+
+		Convey("Should find its own and parent containers", func() {
+			parent.Containers = ContainerList{{ID: "1111", Name: "/shared"}}
+			child.Containers = ContainerList{{ID: "2222", Name: "/TestApi_get_user_db"}}
+
+			container, ok := child.GetContainer("db")
+			So(ok, ShouldBeTrue)
+			So(container.ID, ShouldEqual, "2222")
+
+			container, ok = child.GetContainer("shared")
+			So(ok, ShouldBeTrue)
+			So(container.ID, ShouldEqual, "1111")
+
+			_, ok = parent.GetContainer("db")
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("Should run parent hooks and report to the parent", func() {
+			var events []string
+			parent.After(EventStart, func(op *Operation) { events = append(events, "parent") })
+			child.After(EventStart, func(op *Operation) { events = append(events, "child") })
+
+			op := &Operation{Event: EventStart, Image: "busybox"}
+			So(child.before(op), ShouldBeNil)
+			So(child.after(op, nil), ShouldBeNil)
+
+			So(events, ShouldResemble, []string{"parent", "child"})
+			So(child.Report().Timings, ShouldHaveLength, 1)
+			So(parent.Report().Timings, ShouldHaveLength, 1)
+		})
+
+		Convey("Should scope options set by hooks", func() {
+			client, err := dc.NewClient("tcp://127.0.0.1:1")
+			So(err, ShouldBeNil)
+			parent.Client, child.Client = client, client
+
+			child.Before(EventCreate, func(op *Operation) error {
+				op.Options.Name = "cache"
+				op.Options.HostConfig = &dc.HostConfig{Binds: []string{"cache:/cache"}}
+				return nil
+			})
+			var created *dc.CreateContainerOptions
+			child.After(EventCreate, func(op *Operation) { created = op.Options })
+
+			_, err = child.createContainer(dc.CreateContainerOptions{Config: &dc.Config{}})
+			So(err, ShouldNotBeNil)
+			So(created.Name, ShouldEqual, "TestApi_get_user_cache")
+			So(created.HostConfig.Binds, ShouldResemble, []string{"TestApi_get_user_cache:/cache"})
+		})
+
+		Convey("Should reject a duplicate name", func() {
+			parent.children = []*Pool{child}
+
+			_, err := parent.Child("TestApi/get user")
+			So(err, ShouldNotBeNil)
+			So(parent.children, ShouldHaveLength, 1)
+		})
+
+		Convey("Should be removed from the parent when purged", func() {
+			other := parent.newChild("other")
+			parent.children = []*Pool{child, other}
+
+			So(child.PurgeAll(), ShouldBeNil)
+			So(parent.children, ShouldResemble, []*Pool{other})
+		})
+	})
+}
+
+func TestChild(t *testing.T) {
+	Convey("Given a pool with two sub-pools", t, func() {
+		pool, err := NewPool("")
+		So(err, ShouldBeNil)
+
+		first, err := pool.Child("first")
+		So(err, ShouldBeNil)
+		second, err := pool.Child("second")
+		So(err, ShouldBeNil)
+
+		Convey("When running containers with the same name", func() {
+			opts := func() dc.CreateContainerOptions {
+				return dc.CreateContainerOptions{
+					Name:   "app",
+					Config: &dc.Config{Image: testLocalImage},
+				}
+			}
+			c1, err := first.RunContainerWithOpts(opts())
+			So(err, ShouldBeNil)
+			c2, err := second.RunContainerWithOpts(opts())
+			So(err, ShouldBeNil)
+
+			Convey("Should isolate them", func() {
+				So(c1.Name, ShouldEqual, "/first_app")
+				So(c2.Name, ShouldEqual, "/second_app")
+				So(c1.NetworkSettings.Networks, ShouldContainKey, first.Network())
+			})
+
+			Convey("Should purge only resources of a sub-pool", func() {
+				So(first.PurgeAll(), ShouldBeNil)
+				So(first.Containers, ShouldBeEmpty)
+				So(first.Networks, ShouldBeEmpty)
+				So(second.Containers, ShouldHaveLength, 1)
+			})
+		})
+
+		Reset(func() {
+			pool.PurgeAll()
+		})
+	})
+}
@@ -7,9 +7,7 @@ import (
 	"runtime"
 	"strings"
 	"sync"
-	"time"
 
-	"github.com/cenk/backoff"
 	dc "github.com/fsouza/go-dockerclient"
 )
 
@@ -21,9 +19,24 @@ type (
 	Pool struct {
 		Client *dc.Client
 
+		// Session identifies resources created by the pool. It's stored
+		// in the `LabelSession` label of every container, network and volume.
+		Session string
+
 		rw         sync.RWMutex
 		Containers ContainerList
 		Networks   []*dc.Network
+		Volumes    []*dc.Volume
+
+		hooks  poolHooks
+		report Report
+
+		// name, network, parent and children are set for sub-pools
+		// created by `Child()`.
+		name     string
+		network  string
+		parent   *Pool
+		children []*Pool
 	}
 
 	// Env is a list of environment variables in format NAME=VALUE.
@@ -71,36 +84,45 @@ func NewPool(endpoint string) (*Pool, error) {
 		return nil, err
 	}
 
-	return &Pool{Client: dc}, nil
+	return &Pool{Client: dc, Session: newSessionID()}, nil
 }
 
 // PullImage pulls image from the Docker Hub.
+// Use `PullImages()` to pull multiple images or choose a platform.
 func (p *Pool) PullImage(image string) error {
-	imageName, tag := parseImageName(image)
-	return p.Client.PullImage(dc.PullImageOptions{
-		Repository: imageName,
-		Tag:        tag,
-	}, dc.AuthConfiguration{})
+	return p.pullImage(image, PullOptions{})
 }
 
-// RunContainer runs a container with a given image and env vars.
-// It's a short version of `RunContainerWithOpts()`.
-func (p *Pool) RunContainer(
-	image string, env Env, pullImage bool,
-) (*dc.Container, error) {
+// ensureImage checks if the image exists locally and pulls it if it's
+// missing and `pullImage` is true.
+func (p *Pool) ensureImage(image string, pullImage bool) error {
 	_, err := p.Client.InspectImage(image)
 	if err != nil {
 		if !pullImage {
-			return nil, err
+			return err
 		}
 
-		err := p.PullImage(image)
-		if err != nil {
-			return nil, err
-		}
+		return p.PullImage(image)
+	}
+
+	p.recordImage(image, false)
+
+	return nil
+}
+
+// RunContainer runs a container with a given image and env vars.
+// It's a short version of `RunContainerWithOpts()`.
+// Optional `opts` can be used to mount volumes, host paths and tmpfs
+// or to publish ports on fixed host ports. Exposed ports without
+// a mapping are published on random host ports.
+func (p *Pool) RunContainer(
+	image string, env Env, pullImage bool, opts ...RunOption,
+) (*dc.Container, error) {
+	if err := p.ensureImage(image, pullImage); err != nil {
+		return nil, err
 	}
 
-	return p.RunContainerWithOpts(dc.CreateContainerOptions{
+	createOpts := dc.CreateContainerOptions{
 		Config: &dc.Config{
 			Image: image,
 			Env:   env,
@@ -109,25 +131,80 @@ func (p *Pool) RunContainer(
 			PublishAllPorts: true,
 			AutoRemove:      false,
 		},
-	})
+	}
+	for _, opt := range opts {
+		opt.apply(&createOpts)
+	}
+
+	return p.RunContainerWithOpts(createOpts)
 }
 
 // RunContainerWithOpts runs a container based on given options.
 func (p *Pool) RunContainerWithOpts(
 	opts dc.CreateContainerOptions,
 ) (*dc.Container, error) {
-	container, err := p.Client.CreateContainer(opts)
+	container, err := p.createContainer(opts)
 	if err != nil {
 		return nil, err
 	}
 
-	err = p.Client.StartContainer(container.ID, nil)
-	if err != nil {
+	return p.startContainer(container)
+}
+
+// createContainer creates a container with the pool labels.
+// The container is not tracked until it's started. Explicit host ports
+// are checked against containers of the root pool and its sub-pools.
+func (p *Pool) createContainer(
+	opts dc.CreateContainerOptions,
+) (*dc.Container, error) {
+	op := &Operation{Event: EventCreate, Options: &opts}
+	if opts.Config != nil {
+		config := *opts.Config
+		config.Labels = p.labels(config.Labels)
+		opts.Config = &config
+		op.Image = config.Image
+	}
+
+	// Options changed by hooks are scoped as well.
+	if err := p.before(op); err != nil {
 		return nil, err
 	}
+	p.scopeOptions(&opts)
+	if err := p.checkPortConflicts(opts.HostConfig); err != nil {
+		return nil, p.after(op, err)
+	}
 
-	container, err = p.Client.InspectContainer(container.ID)
+	container, err := p.Client.CreateContainer(opts)
+	op.Container = container
+
+	return container, p.after(op, err)
+}
+
+// startContainer starts a created container and adds it to the pool.
+// If it fails to start, the container is removed.
+func (p *Pool) startContainer(container *dc.Container) (*dc.Container, error) {
+	remove := func() {
+		p.Client.RemoveContainer(dc.RemoveContainerOptions{
+			ID:    container.ID,
+			Force: true,
+		})
+	}
+
+	op := &Operation{Event: EventStart, Container: container}
+	if err := p.before(op); err != nil {
+		remove()
+		return nil, err
+	}
+
+	err := p.Client.StartContainer(container.ID, nil)
 	if err != nil {
+		remove()
+		return nil, p.after(op, portConflictFromDocker(err))
+	}
+
+	container, err = p.Client.InspectContainer(container.ID)
+	op.Container = container
+	if err := p.after(op, err); err != nil {
 		return nil, err
 	}
 
@@ -198,51 +275,61 @@ func (p *Pool) PurgeContainers(containers ContainerList) error {
 }
 
 // PurgeContainer stops and removes container from the docker.
+// Containers which are already stopped are just removed.
 func (p *Pool) PurgeContainer(container *dc.Container) error {
+	return p.purgeContainer(container, true)
+}
+
+// purgeContainer kills and removes the container. Anonymous volumes
+// are removed if `removeVolumes` is true.
+func (p *Pool) purgeContainer(container *dc.Container, removeVolumes bool) error {
+	op := &Operation{Event: EventPurge, Container: container}
+	if err := p.before(op); err != nil {
+		return err
+	}
+
 	if err := p.Client.KillContainer(dc.KillContainerOptions{
 		ID: container.ID,
 	}); err != nil {
-		return err
+		if _, ok := err.(*dc.ContainerNotRunning); !ok {
+			return p.after(op, err)
+		}
 	}
 
 	if err := p.Client.RemoveContainer(dc.RemoveContainerOptions{
 		ID:            container.ID,
 		Force:         true,
-		RemoveVolumes: true,
+		RemoveVolumes: removeVolumes,
 	}); err != nil {
-		return err
+		return p.after(op, err)
 	}
 
 	p.rw.Lock()
 	p.Containers = p.Containers.Remove(container)
 	p.rw.Unlock()
 
-	return nil
-}
-
-// GetContainer returns container struct by name.
-// Remember to preceed name with `/`. This is how go-dockerclient works.
-func (p *Pool) GetContainer(name string) (*dc.Container, bool) {
-	for _, container := range p.Containers {
-		if container.Name[1:] == name {
-			return container, true
-		}
-	}
-
-	return nil, false
+	return p.after(op, nil)
 }
 
 // CreateNetwork creates a new network in the docker.
 func (p *Pool) CreateNetwork(name string) (*dc.Network, error) {
+	name = p.scopedName(name)
+	op := &Operation{Event: EventCreateNetwork, NetworkName: name}
+	if err := p.before(op); err != nil {
+		return nil, err
+	}
+
 	net, err := p.Client.CreateNetwork(dc.CreateNetworkOptions{
-		Name: name,
+		Name:   name,
+		Labels: p.labels(nil),
 	})
 	if err != nil {
-		return nil, err
+		return nil, p.after(op, err)
 	}
 
 	net, err = p.Client.NetworkInfo(net.ID)
-	if err != nil {
+	op.Network = net
+	if err := p.after(op, err); err != nil {
 		return nil, err
 	}
 
@@ -255,9 +342,14 @@ func (p *Pool) CreateNetwork(name string) (*dc.Network, error) {
 
 // PurgeNetwork removes network from the container.
 func (p *Pool) PurgeNetwork(net *dc.Network) error {
+	op := &Operation{Event: EventPurgeNetwork, NetworkName: net.Name, Network: net}
+	if err := p.before(op); err != nil {
+		return err
+	}
+
 	err := p.Client.RemoveNetwork(net.ID)
 	if err != nil {
-		return err
+		return p.after(op, err)
 	}
 
 	// Remove `net` from `p.Networks`.
@@ -272,7 +364,7 @@ func (p *Pool) PurgeNetwork(net *dc.Network) error {
 	p.Networks = nets
 	p.rw.Unlock()
 
-	return nil
+	return p.after(op, nil)
 }
 
 //
@@ -281,6 +373,17 @@ func (p *Pool) PurgeAll() error {
 	var wg sync.WaitGroup
 	var errCh chan error
 
+	// Purge sub-pools first as their containers may use
+	// networks and volumes of this pool.
+	p.rw.RLock()
+	children := p.children
+	p.rw.RUnlock()
+	for _, child := range children {
+		if err := child.PurgeAll(); err != nil {
+			return err
+		}
+	}
+
 	// Purge containers.
 	errCh = make(chan error, len(p.Containers))
 	for _, container := range p.Containers {
@@ -317,36 +420,42 @@ func (p *Pool) PurgeAll() error {
 		return err
 	}
 
-	return nil
-}
-
-// Retry runs `op` every x seconds using exponential back-off strategy.
-func Retry(maxWait time.Duration, op func() error) error {
-	bo := backoff.NewExponentialBackOff()
-	bo.MaxInterval = time.Second * 5
-	bo.MaxElapsedTime = maxWait
-	return backoff.Retry(op, bo)
-}
+	// Purge volumes. It must happen after containers are removed
+	// as volumes in use can't be removed.
+	errCh = make(chan error, len(p.Volumes))
+	for _, vol := range p.Volumes {
+		wg.Add(1)
+		go func(vol *dc.Volume) {
+			defer wg.Done()
+			if errPurge := p.PurgeVolume(vol); errPurge != nil {
+				errCh <- errPurge
+			}
+		}(vol)
+	}
 
-// GetPort returns a bound host port in the container. `id` is an id of
-// the exposed port in the container.
-func GetPort(container *dc.Container, id string) string {
-	if container.NetworkSettings == nil {
-		return ""
+	wg.Wait()
+	close(errCh)
+	if err := <-errCh; err != nil {
+		return err
 	}
 
-	port := dc.Port(id)
-	portBinding, ok := container.NetworkSettings.Ports[port]
-	if !ok {
-		return ""
-	} else if len(portBinding) == 0 {
-		return ""
+	// A purged sub-pool is removed from its parent.
+	if p.parent != nil {
+		p.parent.removeChild(p)
 	}
 
-	return portBinding[0].HostPort
+	return nil
+}
+
+// GetPort returns a bound host port in the container. `id` is an id of
+// the exposed port in the container, for example "5432/tcp" or "53/udp".
+// The protocol defaults to tcp.
+func GetPort(container *dc.Container, id string) string {
+	_, port, _ := GetHostPort(container, id, false)
+	return port
 }
 
-// GetServiceAddr returns a local host with port for the container.
+// GetServiceAddr returns an HTTP address of the published port.
 func GetServiceAddr(container *dc.Container, portID string) string {
-	return "http://127.0.0.1:" + GetPort(container, portID)
+	return GetAddr(container, portID, "http")
 }
@@ -0,0 +1,238 @@
+// Command dockertest inspects and cleans up docker resources created
+// by the dockertest package.
+//
+// Usage:
+//
+//	dockertest ls [-session ID]
+//	dockertest logs [-tail N] [-f] CONTAINER
+//	dockertest purge [-session ID] [-older-than DURATION] [-all]
+//	dockertest pull -f MANIFEST [-policy POLICY] [-platform PLATFORM] [-concurrency N] [-v]
+//	dockertest up -f MANIFEST
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/adambabik/go-collections/dockertest"
+)
+
+var endpoint = flag.String("endpoint", "", "docker endpoint; defaults to DOCKER_URL or the local socket")
+
+type command struct {
+	usage string
+	run   func(pool *dockertest.Pool, args []string) error
+}
+
+var commands = map[string]command{
+	"ls":    {"list resources by session", runList},
+	"logs":  {"print logs of a container", runLogs},
+	"purge": {"remove resources by session or age", runPurge},
+	"pull":  {"pull images declared in a manifest", runPull},
+	"up":    {"start an environment from a manifest", runUp},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: dockertest [-endpoint URL] COMMAND [ARGS]\n\nCommands:\n")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-6s %s\n", name, commands[name].usage)
+	}
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+
+	pool, err := dockertest.NewPool(*endpoint)
+	if err != nil {
+		fatal(err)
+	}
+
+	if err := cmd.run(pool, flag.Args()[1:]); err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "dockertest: %v\n", err)
+	os.Exit(1)
+}
+
+func runList(pool *dockertest.Pool, args []string) error {
+	fs := flag.NewFlagSet("ls", flag.ExitOnError)
+	session := fs.String("session", "", "session id; all sessions if empty")
+	fs.Parse(args)
+
+	res, err := pool.ListResources(*session)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TYPE\tID\tNAME\tSESSION\tCREATED\tSTATUS")
+	for _, c := range res.Containers {
+		fmt.Fprintf(w, "container\t%s\t%s\t%s\t%s\t%s\n",
+			shortID(c.ID), strings.Join(c.Names, ","),
+			c.Labels[dockertest.LabelSession], age(c.Labels), c.Status)
+	}
+	for _, n := range res.Networks {
+		fmt.Fprintf(w, "network\t%s\t%s\t%s\t%s\t\n",
+			shortID(n.ID), n.Name,
+			n.Labels[dockertest.LabelSession], age(n.Labels))
+	}
+	for _, v := range res.Volumes {
+		fmt.Fprintf(w, "volume\t\t%s\t%s\t%s\t\n",
+			v.Name, v.Labels[dockertest.LabelSession], age(v.Labels))
+	}
+
+	return w.Flush()
+}
+
+func runLogs(pool *dockertest.Pool, args []string) error {
+	fs := flag.NewFlagSet("logs", flag.ExitOnError)
+	tail := fs.String("tail", "all", "number of lines to show from the end of logs")
+	follow := fs.Bool("f", false, "follow log output")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return errors.New("logs requires exactly one container")
+	}
+
+	return pool.Logs(os.Stdout, fs.Arg(0), *tail, *follow)
+}
+
+func runPurge(pool *dockertest.Pool, args []string) error {
+	fs := flag.NewFlagSet("purge", flag.ExitOnError)
+	session := fs.String("session", "", "purge resources from the session")
+	olderThan := fs.Duration("older-than", 0, "purge resources older than the duration")
+	all := fs.Bool("all", false, "purge resources from all sessions")
+	fs.Parse(args)
+
+	if *session == "" && *olderThan == 0 && !*all {
+		return errors.New("purge requires -session, -older-than or -all")
+	}
+
+	res, err := pool.ListResources(*session)
+	if err != nil {
+		return err
+	}
+	if *olderThan > 0 {
+		res = res.OlderThan(*olderThan)
+	}
+
+	fmt.Printf("Removing %d containers, %d networks and %d volumes\n",
+		len(res.Containers), len(res.Networks), len(res.Volumes))
+
+	return pool.PurgeResources(res)
+}
+
+func loadManifest(fs *flag.FlagSet, args []string) (*dockertest.Manifest, error) {
+	filename := fs.String("f", "", "manifest file")
+	fs.Parse(args)
+
+	if *filename == "" {
+		return nil, fmt.Errorf("%s requires a manifest (-f)", fs.Name())
+	}
+
+	return dockertest.LoadManifest(*filename)
+}
+
+func runPull(pool *dockertest.Pool, args []string) error {
+	fs := flag.NewFlagSet("pull", flag.ExitOnError)
+	policy := fs.String("policy", string(dockertest.PullIfNotPresent), "pull policy: always, if-not-present or never")
+	platform := fs.String("platform", "", "image platform, for example linux/arm64")
+	concurrency := fs.Int("concurrency", 4, "number of images pulled at once")
+	verbose := fs.Bool("v", false, "print layer progress")
+
+	m, err := loadManifest(fs, args)
+	if err != nil {
+		return err
+	}
+	pullPolicy, err := dockertest.ParsePullPolicy(*policy)
+	if err != nil {
+		return err
+	}
+
+	return pool.PullImages(m.Images(), dockertest.PullOptions{
+		Policy:      pullPolicy,
+		Platform:    *platform,
+		Concurrency: *concurrency,
+		Progress: func(p dockertest.PullProgress) {
+			if p.Total > 0 && !*verbose {
+				return
+			}
+			fmt.Printf("%s: %s %s\n", p.Image, p.Layer, p.Status)
+		},
+	})
+}
+
+func runUp(pool *dockertest.Pool, args []string) error {
+	m, err := loadManifest(flag.NewFlagSet("up", flag.ExitOnError), args)
+	if err != nil {
+		return err
+	}
+
+	env, err := pool.StartManifest(m)
+	if err != nil {
+		pool.PurgeAll()
+		return err
+	}
+
+	services := make([]string, 0, len(env.Services))
+	for name := range env.Services {
+		services = append(services, name)
+	}
+	sort.Strings(services)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SERVICE\tCONTAINER\tADDRESSES")
+	for _, name := range services {
+		var addrs []string
+		for port, addr := range env.Addrs[name] {
+			addrs = append(addrs, port+"="+addr)
+		}
+		sort.Strings(addrs)
+		fmt.Fprintf(w, "%s\t%s\t%s\n", name, shortID(env.Services[name].ID()), strings.Join(addrs, ", "))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Printf("\nSession %s. Run `dockertest purge -session %s` to clean up.\n",
+		pool.Session, pool.Session)
+
+	return nil
+}
+
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+func age(labels map[string]string) string {
+	created := dockertest.CreatedAt(labels)
+	if created.IsZero() {
+		return ""
+	}
+	return (time.Since(created) / time.Second * time.Second).String()
+}
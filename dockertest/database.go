@@ -0,0 +1,323 @@
+package dockertest
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type (
+	// DSNFunc builds a connection string for a started container.
+	DSNFunc func(r *Resource) string
+
+	// InitFunc initializes a database given a ready connection string.
+	InitFunc func(dsn string) error
+
+	// Database describes how to connect to a database in a container.
+	// Driver is a `database/sql` driver name, for example "postgres"
+	// or "mysql". The driver must be registered by the caller.
+	Database struct {
+		Driver string
+		DSN    DSNFunc
+	}
+
+	// initStep is either a SQL file, a directory with SQL files
+	// or a callback.
+	initStep struct {
+		path string
+		fn   InitFunc
+	}
+
+	// Statement is a single SQL statement read from a script.
+	Statement struct {
+		// Line is a line number where the statement starts.
+		Line int
+		SQL  string
+	}
+
+	// ScriptError is returned when an init script fails.
+	ScriptError struct {
+		Script    string
+		Statement int
+		Line      int
+		SQL       string
+		Err       error
+	}
+)
+
+func (e *ScriptError) Error() string {
+	if e.Statement == 0 {
+		return fmt.Sprintf("%s: %v", e.Script, e.Err)
+	}
+
+	return fmt.Sprintf("%s:%d: statement %d: %v\n%s",
+		e.Script, e.Line, e.Statement, e.Err, e.SQL)
+}
+
+// PostgresDSN returns a `DSNFunc` for a Postgres container publishing
+// 5432/tcp. It's meant to be used with github.com/lib/pq.
+func PostgresDSN(user, password, dbname string) DSNFunc {
+	return func(r *Resource) string {
+		return fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=disable",
+			user, password, r.Addr("5432/tcp"), dbname)
+	}
+}
+
+// MySQLDSN returns a `DSNFunc` for a MySQL container publishing
+// 3306/tcp. It's meant to be used with github.com/go-sql-driver/mysql.
+func MySQLDSN(user, password, dbname string) DSNFunc {
+	return func(r *Resource) string {
+		return fmt.Sprintf("%s:%s@tcp(%s)/%s",
+			user, password, r.Addr("3306/tcp"), dbname)
+	}
+}
+
+// WithDatabase sets how to connect to the database in the container.
+// When set, `Run()` waits until the database accepts connections
+// and then runs init scripts.
+func (b *ContainerBuilder) WithDatabase(driver string, dsn DSNFunc) *ContainerBuilder {
+	b.database = &Database{Driver: driver, DSN: dsn}
+	return b
+}
+
+// WithInitScripts adds SQL files or directories. Files in a directory
+// with .sql extension are run in the name order. Scripts and callbacks
+// run in the order they were added.
+func (b *ContainerBuilder) WithInitScripts(paths ...string) *ContainerBuilder {
+	for _, path := range paths {
+		b.init = append(b.init, initStep{path: path})
+	}
+	return b
+}
+
+// WithInitFunc adds a callback called with a ready connection string.
+func (b *ContainerBuilder) WithInitFunc(fn InitFunc) *ContainerBuilder {
+	b.init = append(b.init, initStep{fn: fn})
+	return b
+}
+
+// initDatabase waits for the database and runs init steps
+// if `runInit` is true.
+func (b *ContainerBuilder) initDatabase(r *Resource, runInit bool) error {
+	dsn := b.database.DSN(r)
+
+	db, err := sql.Open(b.database.Driver, dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	timeout := b.waitTimeout
+	if timeout == 0 {
+		timeout = defaultReadyTimeout
+	}
+	if err := Retry(timeout, db.Ping); err != nil {
+		return err
+	}
+	if !runInit {
+		return nil
+	}
+
+	funcs := 0
+	for _, step := range b.init {
+		if step.fn != nil {
+			funcs++
+			if err := step.fn(dsn); err != nil {
+				return &ScriptError{Script: fmt.Sprintf("init func #%d", funcs), Err: err}
+			}
+			continue
+		}
+
+		files, err := sqlFiles(step.path)
+		if err != nil {
+			return &ScriptError{Script: step.path, Err: err}
+		}
+		for _, file := range files {
+			if err := runScript(db, b.database.Driver, file); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// sqlFiles returns `path` if it's a file or sorted .sql files
+// if it's a directory.
+func sqlFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(path, "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	return files, nil
+}
+
+func runScript(db *sql.DB, driver, filename string) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return &ScriptError{Script: filename, Err: err}
+	}
+
+	for i, stmt := range SplitStatements(driver, string(data)) {
+		if _, err := db.Exec(stmt.SQL); err != nil {
+			return &ScriptError{
+				Script:    filename,
+				Statement: i + 1,
+				Line:      stmt.Line,
+				SQL:       stmt.SQL,
+				Err:       err,
+			}
+		}
+	}
+
+	return nil
+}
+
+// SplitStatements splits a SQL script of a `database/sql` driver into
+// statements separated by `;`. It skips separators in quotes, comments
+// and Postgres dollar-quoted strings. Backslashes escape quotes in
+// strings of the "mysql" driver and in Postgres escape strings like
+// E'it\'s'; other drivers treat them literally. Comments and empty
+// statements are omitted.
+func SplitStatements(driver, script string) []Statement {
+	var (
+		mysql = driver == "mysql"
+		stmts []Statement
+		buf   bytes.Buffer
+		line  = 1
+		start = 0
+	)
+
+	// write adds `s` to the current statement. `s` starts at `line`.
+	write := func(s string) {
+		if start == 0 && strings.TrimSpace(s) != "" {
+			start = line
+		}
+		buf.WriteString(s)
+		line += strings.Count(s, "\n")
+	}
+
+	for i := 0; i < len(script); {
+		var end int
+
+		switch c := script[i]; {
+		case c == ';':
+			if sql := strings.TrimSpace(buf.String()); sql != "" {
+				stmts = append(stmts, Statement{Line: start, SQL: sql})
+			}
+			buf.Reset()
+			start = 0
+			i++
+			continue
+		case strings.HasPrefix(script[i:], "--"):
+			end = strings.IndexByte(script[i:], '\n')
+			if end == -1 {
+				end = len(script) - i
+			}
+			i += end
+			continue
+		case strings.HasPrefix(script[i:], "/*"):
+			end = strings.Index(script[i+2:], "*/")
+			if end == -1 {
+				end = len(script) - i
+			} else {
+				end += 4
+			}
+			line += strings.Count(script[i:i+end], "\n")
+			i += end
+			continue
+		case c == '\'' || c == '"' || c == '`':
+			escapes := mysql && c != '`' || c == '\'' && isEscapeString(script, i)
+			end = quoteEnd(script, i, c, escapes)
+		case c == '$' && dollarTag(script[i:]) != "":
+			tag := dollarTag(script[i:])
+			end = strings.Index(script[i+len(tag):], tag)
+			if end == -1 {
+				end = len(script)
+			} else {
+				end += i + 2*len(tag)
+			}
+		default:
+			end = i + 1
+		}
+
+		write(script[i:end])
+		i = end
+	}
+
+	if sql := strings.TrimSpace(buf.String()); sql != "" {
+		stmts = append(stmts, Statement{Line: start, SQL: sql})
+	}
+
+	return stmts
+}
+
+// quoteEnd returns an index after the closing quote. Doubled quotes
+// are treated as a part of the string, as are backslash escapes
+// if `escapes` is true.
+func quoteEnd(s string, start int, quote byte, escapes bool) int {
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if escapes {
+				i++
+			}
+		case quote:
+			if i+1 < len(s) && s[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+
+	return len(s)
+}
+
+// isEscapeString returns true if the quote at `i` starts a Postgres
+// escape string like E'it\'s'. Backslashes are literal in standard
+// strings, for example 'C:\'.
+func isEscapeString(s string, i int) bool {
+	if i == 0 || (s[i-1] != 'E' && s[i-1] != 'e') {
+		return false
+	}
+	return i == 1 || !isIdentChar(s[i-2])
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '$' || c >= '0' && c <= '9' ||
+		c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// dollarTag returns a dollar quote tag like $$ or $body$ at the beginning
+// of `s` or an empty string.
+func dollarTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '$':
+			return s[:i+1]
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' ||
+			i > 1 && c >= '0' && c <= '9':
+		default:
+			return ""
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,108 @@
+package dockertest
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSplitStatements(t *testing.T) {
+	Convey("Given a SQL script", t, func() {
+		script := `-- schema
+CREATE TABLE users (
+  id serial PRIMARY KEY,
+  name text DEFAULT 'a;b'
+);
+
+/* multi
+   line; comment */
+INSERT INTO users (name) VALUES ('it''s; fine');
+
+CREATE FUNCTION f() RETURNS void AS $body$
+BEGIN
+  PERFORM 1;
+END;
+$body$ LANGUAGE plpgsql;
+SELECT $1::int;
+;
+`
+
+		Convey("Should split it into statements with line numbers", func() {
+			stmts := SplitStatements("postgres", script)
+			So(stmts, ShouldHaveLength, 4)
+
+			So(stmts[0].Line, ShouldEqual, 2)
+			So(stmts[0].SQL, ShouldStartWith, "CREATE TABLE users (")
+			So(stmts[0].SQL, ShouldEndWith, "DEFAULT 'a;b'\n)")
+
+			So(stmts[1].Line, ShouldEqual, 9)
+			So(stmts[1].SQL, ShouldEqual, "INSERT INTO users (name) VALUES ('it''s; fine')")
+
+			So(stmts[2].Line, ShouldEqual, 11)
+			So(stmts[2].SQL, ShouldContainSubstring, "PERFORM 1;\nEND;")
+
+			So(stmts[3].Line, ShouldEqual, 16)
+			So(stmts[3].SQL, ShouldEqual, "SELECT $1::int")
+		})
+	})
+
+	Convey("Given a script without a trailing separator", t, func() {
+		Convey("Should return the last statement", func() {
+			stmts := SplitStatements("postgres", "SELECT 1; SELECT 2")
+			So(stmts, ShouldResemble, []Statement{
+				{Line: 1, SQL: "SELECT 1"},
+				{Line: 1, SQL: "SELECT 2"},
+			})
+		})
+	})
+
+	Convey("Given strings with backslashes", t, func() {
+		Convey("Should treat them as escapes only in Postgres escape strings", func() {
+			stmts := SplitStatements("postgres", `SELECT 'C:\'; SELECT E'it\'s; fine'; SELECT name'x\'`)
+			So(stmts, ShouldResemble, []Statement{
+				{Line: 1, SQL: `SELECT 'C:\'`},
+				{Line: 1, SQL: `SELECT E'it\'s; fine'`},
+				{Line: 1, SQL: `SELECT name'x\'`},
+			})
+		})
+
+		Convey("Should treat them as escapes in MySQL strings", func() {
+			stmts := SplitStatements("mysql", `SELECT 'it\'s; x'; SELECT "a\"; b", `+"`c\\`"+`; SELECT 'C:\\'`)
+			So(stmts, ShouldResemble, []Statement{
+				{Line: 1, SQL: `SELECT 'it\'s; x'`},
+				{Line: 1, SQL: `SELECT "a\"; b", ` + "`c\\`"},
+				{Line: 1, SQL: `SELECT 'C:\\'`},
+			})
+		})
+	})
+}
+
+func TestScriptError(t *testing.T) {
+	Convey("Given a script error", t, func() {
+		err := &ScriptError{
+			Script:    "testdata/init/schema.sql",
+			Statement: 2,
+			Line:      5,
+			SQL:       "SELECT x",
+			Err:       errors.New(`column "x" does not exist`),
+		}
+
+		Convey("Should point at the script and statement", func() {
+			So(err.Error(), ShouldEqual,
+				"testdata/init/schema.sql:5: statement 2: column \"x\" does not exist\nSELECT x")
+		})
+	})
+}
+
+func TestInitScriptsRequireDatabase(t *testing.T) {
+	Convey("Given a builder with init scripts and no database", t, func() {
+		_, err := NewContainer("postgres:9.6").
+			WithInitScripts("testdata/init").
+			Run(&Pool{})
+
+		Convey("Should report an error", func() {
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
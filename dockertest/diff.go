@@ -0,0 +1,286 @@
+package dockertest
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	dc "github.com/fsouza/go-dockerclient"
+)
+
+type (
+	// Changes are paths changed in a container filesystem compared
+	// to its image. Paths are sorted.
+	Changes struct {
+		Added    []string
+		Modified []string
+		Deleted  []string
+	}
+
+	// DiffMismatchError is returned by `Changes.Compare()`. Entries are
+	// in the golden list format, for example "A /out/report.json".
+	DiffMismatchError struct {
+		// Missing are golden entries which didn't match any change.
+		Missing []string
+
+		// Unexpected are changes which didn't match any golden entry.
+		Unexpected []string
+	}
+)
+
+// Diff returns filesystem changes of the container. Docker reports
+// parent directories of added and deleted paths as modified.
+func (p *Pool) Diff(container *dc.Container) (*Changes, error) {
+	list, err := p.Client.ContainerChanges(container.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return newChanges(list), nil
+}
+
+func newChanges(list []dc.Change) *Changes {
+	c := &Changes{}
+	for _, change := range list {
+		switch change.Kind {
+		case dc.ChangeAdd:
+			c.Added = append(c.Added, change.Path)
+		case dc.ChangeModify:
+			c.Modified = append(c.Modified, change.Path)
+		case dc.ChangeDelete:
+			c.Deleted = append(c.Deleted, change.Path)
+		}
+	}
+
+	sort.Strings(c.Added)
+	sort.Strings(c.Modified)
+	sort.Strings(c.Deleted)
+
+	return c
+}
+
+// Lines returns changes in the golden list format used by
+// `docker diff`: "A path", "C path" or "D path", sorted by path.
+func (c *Changes) Lines() []string {
+	lines := make([]string, 0, len(c.Added)+len(c.Modified)+len(c.Deleted))
+	for _, p := range c.Added {
+		lines = append(lines, "A "+p)
+	}
+	for _, p := range c.Modified {
+		lines = append(lines, "C "+p)
+	}
+	for _, p := range c.Deleted {
+		lines = append(lines, "D "+p)
+	}
+
+	sort.Sort(byPath(lines))
+
+	return lines
+}
+
+// byPath sorts golden list entries by path.
+type byPath []string
+
+func (s byPath) Len() int           { return len(s) }
+func (s byPath) Less(i, j int) bool { return s[i][2:] < s[j][2:] }
+func (s byPath) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// Under returns changes of paths under any of `dirs`, for example
+// to ignore files written by the system in /etc or /tmp.
+func (c *Changes) Under(dirs ...string) *Changes {
+	filter := func(paths []string) []string {
+		var result []string
+		for _, p := range paths {
+			for _, dir := range dirs {
+				dir = strings.TrimSuffix(dir, "/")
+				if p == dir || strings.HasPrefix(p, dir+"/") {
+					result = append(result, p)
+					break
+				}
+			}
+		}
+		return result
+	}
+
+	return &Changes{
+		Added:    filter(c.Added),
+		Modified: filter(c.Modified),
+		Deleted:  filter(c.Deleted),
+	}
+}
+
+// Compare compares changes with a golden list in the `Lines()` format.
+// Paths in golden entries can be `path.Match` patterns, for example
+// "A /out/*.json". It returns `*DiffMismatchError` if a golden entry
+// matches no change or a change matches no golden entry.
+func (c *Changes) Compare(golden []string) error {
+	lines := c.Lines()
+	matched := make([]bool, len(lines))
+	mismatch := &DiffMismatchError{}
+
+	for _, entry := range golden {
+		found := false
+		for i, line := range lines {
+			if len(entry) < 3 || line[:2] != entry[:2] {
+				continue
+			}
+			if ok, _ := path.Match(entry[2:], line[2:]); ok {
+				matched[i] = true
+				found = true
+			}
+		}
+		if !found {
+			mismatch.Missing = append(mismatch.Missing, entry)
+		}
+	}
+
+	for i, line := range lines {
+		if !matched[i] {
+			mismatch.Unexpected = append(mismatch.Unexpected, line)
+		}
+	}
+
+	if len(mismatch.Missing) > 0 || len(mismatch.Unexpected) > 0 {
+		return mismatch
+	}
+
+	return nil
+}
+
+func (e *DiffMismatchError) Error() string {
+	var buf bytes.Buffer
+	buf.WriteString("dockertest: filesystem changes don't match")
+	for _, entry := range e.Missing {
+		buf.WriteString("\n  missing:    " + entry)
+	}
+	for _, line := range e.Unexpected {
+		buf.WriteString("\n  unexpected: " + line)
+	}
+	return buf.String()
+}
+
+// LoadGolden reads a golden list of changes from a file. Empty lines
+// and lines starting with `#` are skipped.
+func LoadGolden(filename string) ([]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var golden []string
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if len(line) < 3 || !strings.ContainsAny(line[:1], "ACD") || line[1] != ' ' {
+			return nil, fmt.Errorf("%s:%d: invalid entry %q", filename, n, line)
+		}
+		golden = append(golden, line)
+	}
+
+	return golden, scanner.Err()
+}
+
+// ExtractChanges copies added and modified files of the container
+// to `dir`, preserving their paths, for example /out/report.json is
+// written to dir/out/report.json. Directories are created, but only
+// files reported as changed are copied. The container can be stopped.
+func (p *Pool) ExtractChanges(container *dc.Container, changes *Changes, dir string) error {
+	changed := make(map[string]bool)
+	for _, name := range changes.Added {
+		changed[name] = true
+	}
+	for _, name := range changes.Modified {
+		changed[name] = true
+	}
+
+	for _, target := range leafPaths(changed) {
+		var buf bytes.Buffer
+		if err := p.Client.DownloadFromContainer(container.ID, dc.DownloadFromContainerOptions{
+			Path:         target,
+			OutputStream: &buf,
+		}); err != nil {
+			return err
+		}
+
+		if err := extractTar(&buf, path.Dir(target), dir, changed); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// leafPaths returns paths which aren't parent directories of other
+// paths, so that modified directories like / aren't downloaded whole.
+func leafPaths(paths map[string]bool) []string {
+	var leaves []string
+	for p := range paths {
+		leaf := true
+		for other := range paths {
+			if strings.HasPrefix(other, strings.TrimSuffix(p, "/")+"/") {
+				leaf = false
+				break
+			}
+		}
+		if leaf {
+			leaves = append(leaves, p)
+		}
+	}
+
+	sort.Strings(leaves)
+	return leaves
+}
+
+// extractTar writes regular files from an archive downloaded from
+// the container directory `base` to `dir`. Only files in `changed`
+// are written.
+func extractTar(r io.Reader, base, dir string, changed map[string]bool) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := path.Join(base, hdr.Name)
+		if hdr.Typeflag != tar.TypeReg || !changed[name] {
+			continue
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+
+		f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, os.FileMode(hdr.Mode)&0777|0600)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(f, tr)
+		if errClose := f.Close(); err == nil {
+			err = errClose
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Diff returns filesystem changes of the container.
+func (r *Resource) Diff() (*Changes, error) {
+	return r.pool.Diff(r.Container)
+}
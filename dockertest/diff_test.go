@@ -0,0 +1,117 @@
+package dockertest
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	dc "github.com/fsouza/go-dockerclient"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestChanges(t *testing.T) {
+	Convey("Given changes reported by docker", t, func() {
+		changes := newChanges([]dc.Change{
+			{Path: "/out", Kind: dc.ChangeModify},
+			{Path: "/out/b.json", Kind: dc.ChangeAdd},
+			{Path: "/out/a.json", Kind: dc.ChangeAdd},
+			{Path: "/etc/old.conf", Kind: dc.ChangeDelete},
+			{Path: "/etc", Kind: dc.ChangeModify},
+		})
+
+		Convey("Should sort them by kind and path", func() {
+			So(changes.Added, ShouldResemble, []string{"/out/a.json", "/out/b.json"})
+			So(changes.Modified, ShouldResemble, []string{"/etc", "/out"})
+			So(changes.Deleted, ShouldResemble, []string{"/etc/old.conf"})
+			So(changes.Lines(), ShouldResemble, []string{
+				"C /etc", "D /etc/old.conf", "C /out", "A /out/a.json", "A /out/b.json",
+			})
+		})
+
+		Convey("Should filter them by directory", func() {
+			So(changes.Under("/out/").Lines(), ShouldResemble, []string{
+				"C /out", "A /out/a.json", "A /out/b.json",
+			})
+		})
+
+		Convey("Should match a golden list with patterns", func() {
+			err := changes.Under("/out").Compare([]string{"C /out", "A /out/*.json"})
+			So(err, ShouldBeNil)
+		})
+
+		Convey("Should report missing and unexpected changes", func() {
+			err := changes.Under("/out").Compare([]string{"A /out/*.json", "A /out/c.txt"})
+			So(err, ShouldHaveSameTypeAs, &DiffMismatchError{})
+			So(err.(*DiffMismatchError).Missing, ShouldResemble, []string{"A /out/c.txt"})
+			So(err.(*DiffMismatchError).Unexpected, ShouldResemble, []string{"C /out"})
+		})
+
+		Convey("Should download only leaf paths", func() {
+			So(leafPaths(map[string]bool{
+				"/": true, "/out": true, "/out/a.json": true, "/tmp/x": true,
+			}), ShouldResemble, []string{"/out/a.json", "/tmp/x"})
+		})
+	})
+}
+
+func TestLoadGolden(t *testing.T) {
+	Convey("Given a golden file", t, func() {
+		f, err := ioutil.TempFile("", "golden")
+		So(err, ShouldBeNil)
+		defer os.Remove(f.Name())
+
+		Convey("Should skip comments and empty lines", func() {
+			f.WriteString("# report files\nA /out/*.json\n\nC /out\n")
+			f.Close()
+
+			golden, err := LoadGolden(f.Name())
+			So(err, ShouldBeNil)
+			So(golden, ShouldResemble, []string{"A /out/*.json", "C /out"})
+		})
+
+		Convey("Should report invalid entries", func() {
+			f.WriteString("A /out\nX /tmp\n")
+			f.Close()
+
+			_, err := LoadGolden(f.Name())
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEndWith, `:2: invalid entry "X /tmp"`)
+		})
+	})
+}
+
+func TestExtractTar(t *testing.T) {
+	Convey("Given an archive of a container directory", t, func() {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for _, f := range []struct{ name, body string }{
+			{"out/a.json", "{}"},
+			{"out/skipped.txt", "old"},
+		} {
+			tw.WriteHeader(&tar.Header{
+				Name: f.name, Mode: 0644, Size: int64(len(f.body)), Typeflag: tar.TypeReg,
+			})
+			tw.Write([]byte(f.body))
+		}
+		tw.Close()
+
+		dir, err := ioutil.TempDir("", "changes")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		Convey("Should extract only changed files", func() {
+			err := extractTar(&buf, "/", dir, map[string]bool{"/out/a.json": true})
+			So(err, ShouldBeNil)
+
+			data, err := ioutil.ReadFile(filepath.Join(dir, "out", "a.json"))
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, "{}")
+
+			_, err = os.Stat(filepath.Join(dir, "out", "skipped.txt"))
+			So(os.IsNotExist(err), ShouldBeTrue)
+		})
+	})
+}
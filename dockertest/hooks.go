@@ -0,0 +1,137 @@
+package dockertest
+
+import (
+	"time"
+
+	dc "github.com/fsouza/go-dockerclient"
+)
+
+// Events which hooks can be registered for.
+const (
+	EventPull          Event = "pull"
+	EventCreate        Event = "create"
+	EventStart         Event = "start"
+	EventReady         Event = "ready"
+	EventPurge         Event = "purge"
+	EventRestart       Event = "restart"
+	EventCreateNetwork Event = "create_network"
+	EventPurgeNetwork  Event = "purge_network"
+)
+
+type (
+	// Event is a pool operation type.
+	Event string
+
+	// Operation describes a pool operation passed to hooks.
+	// Only fields relevant to the event are set.
+	Operation struct {
+		Event Event
+
+		// Image is set for pull and create.
+		Image string
+
+		// Options is set for create. Before-hooks can modify it; names
+		// and volumes are prefixed by sub-pools after hooks run.
+		Options *dc.CreateContainerOptions
+
+		// Container is set for start, ready and purge, and after create.
+		Container *dc.Container
+
+		// NetworkName is set for network operations.
+		// Network is set after create_network and for purge_network.
+		NetworkName string
+		Network     *dc.Network
+
+		// Err is a result of the operation. It's set only for after-hooks.
+		Err error
+
+		started time.Time
+	}
+
+	// Hook is called before a pool operation. It can modify the operation
+	// options or cancel it by returning an error, which is then returned
+	// from the operation.
+	Hook func(op *Operation) error
+
+	// AfterHook is called after a pool operation with its result in `op.Err`.
+	AfterHook func(op *Operation)
+
+	poolHooks struct {
+		before map[Event][]Hook
+		after  map[Event][]AfterHook
+	}
+)
+
+// Before registers a hook called before every `event` operation.
+func (p *Pool) Before(event Event, hook Hook) {
+	p.rw.Lock()
+	if p.hooks.before == nil {
+		p.hooks.before = make(map[Event][]Hook)
+	}
+	p.hooks.before[event] = append(p.hooks.before[event], hook)
+	p.rw.Unlock()
+}
+
+// After registers a hook called after every `event` operation.
+func (p *Pool) After(event Event, hook AfterHook) {
+	p.rw.Lock()
+	if p.hooks.after == nil {
+		p.hooks.after = make(map[Event][]AfterHook)
+	}
+	p.hooks.after[event] = append(p.hooks.after[event], hook)
+	p.rw.Unlock()
+}
+
+// before runs before-hooks for the operation until one returns an error.
+func (p *Pool) before(op *Operation) error {
+	op.started = time.Now()
+
+	for _, hook := range p.beforeHooks(op.Event) {
+		if err := hook(op); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// after runs after-hooks with the operation result `err` and returns `err`.
+func (p *Pool) after(op *Operation, err error) error {
+	op.Err = err
+	p.recordTiming(op)
+	for _, hook := range p.afterHooks(op.Event) {
+		hook(op)
+	}
+
+	return err
+}
+
+// beforeHooks returns before-hooks of parent pools followed by hooks
+// of the pool.
+func (p *Pool) beforeHooks(event Event) []Hook {
+	var hooks []Hook
+	if p.parent != nil {
+		hooks = p.parent.beforeHooks(event)
+	}
+
+	p.rw.RLock()
+	hooks = append(hooks, p.hooks.before[event]...)
+	p.rw.RUnlock()
+
+	return hooks
+}
+
+// afterHooks returns after-hooks of parent pools followed by hooks
+// of the pool.
+func (p *Pool) afterHooks(event Event) []AfterHook {
+	var hooks []AfterHook
+	if p.parent != nil {
+		hooks = p.parent.afterHooks(event)
+	}
+
+	p.rw.RLock()
+	hooks = append(hooks, p.hooks.after[event]...)
+	p.rw.RUnlock()
+
+	return hooks
+}
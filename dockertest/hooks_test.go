@@ -0,0 +1,67 @@
+package dockertest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	dc "github.com/fsouza/go-dockerclient"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHooks(t *testing.T) {
+	Convey("Given a pool with hooks", t, func() {
+		pool, err := NewPool("")
+		So(err, ShouldBeNil)
+
+		var events []string
+		pool.Before(EventCreate, func(op *Operation) error {
+			if !strings.HasPrefix(op.Image, "adambabik/") {
+				return errors.New("image not allowed: " + op.Image)
+			}
+			op.Options.Config.Labels["hooked"] = "yes"
+			return nil
+		})
+		pool.Before(EventPull, func(op *Operation) error {
+			return errors.New("pulling disabled")
+		})
+		for _, event := range []Event{EventCreate, EventStart, EventPurge} {
+			pool.After(event, func(op *Operation) {
+				events = append(events, string(op.Event))
+			})
+		}
+
+		Convey("Should veto pulling an image", func() {
+			err := pool.PullImage(testRemoteImage)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "pulling disabled")
+		})
+
+		Convey("Should veto creating a container from a not allowed image", func() {
+			_, err := pool.RunContainerWithOpts(dc.CreateContainerOptions{
+				Config: &dc.Config{Image: "some/image"},
+			})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "image not allowed: some/image")
+			So(events, ShouldBeEmpty)
+		})
+
+		Convey("When running and purging an allowed container", func() {
+			container, err := pool.RunContainer(testLocalImage, nil, false)
+			So(err, ShouldBeNil)
+			So(pool.PurgeContainer(container), ShouldBeNil)
+
+			Convey("Should apply modified options", func() {
+				So(container.Config.Labels["hooked"], ShouldEqual, "yes")
+			})
+
+			Convey("Should call after-hooks in order", func() {
+				So(events, ShouldResemble, []string{"create", "start", "purge"})
+			})
+		})
+
+		Reset(func() {
+			pool.PurgeAll()
+		})
+	})
+}
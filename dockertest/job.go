@@ -0,0 +1,127 @@
+package dockertest
+
+import (
+	"bytes"
+	"errors"
+	"time"
+
+	dc "github.com/fsouza/go-dockerclient"
+)
+
+// ErrTimeout is returned when a container doesn't finish in time.
+var ErrTimeout = errors.New("dockertest: timed out waiting for container")
+
+// JobResult is a result of a container run to completion.
+type JobResult struct {
+	ExitCode int
+	Stdout   []byte
+	Stderr   []byte
+}
+
+// waitContainer blocks until the container stops and returns its exit code.
+// If `timeout` is greater than zero, it returns `ErrTimeout` after it passes.
+func (p *Pool) waitContainer(id string, timeout time.Duration) (int, error) {
+	type result struct {
+		code int
+		err  error
+	}
+
+	resCh := make(chan result, 1)
+	go func() {
+		code, err := p.Client.WaitContainer(id)
+		resCh <- result{code, err}
+	}()
+
+	if timeout <= 0 {
+		res := <-resCh
+		return res.code, res.err
+	}
+
+	select {
+	case res := <-resCh:
+		return res.code, res.err
+	case <-time.After(timeout):
+		return 0, ErrTimeout
+	}
+}
+
+// StopContainer sends `signal` to the container and waits `grace` for
+// it to stop. If it's still running after that, it's killed with SIGKILL.
+// It returns the container exit code. The container is not removed.
+// If `grace` is zero or negative, the container is killed immediately.
+func (p *Pool) StopContainer(
+	container *dc.Container, signal dc.Signal, grace time.Duration,
+) (int, error) {
+	if grace <= 0 {
+		signal = dc.SIGKILL
+	}
+	if err := p.Client.KillContainer(dc.KillContainerOptions{
+		ID:     container.ID,
+		Signal: signal,
+	}); err != nil {
+		return 0, err
+	}
+
+	// A zero timeout waits until the container stops, which is what
+	// SIGKILL guarantees.
+	code, err := p.waitContainer(container.ID, grace)
+	if err == ErrTimeout {
+		if err := p.Client.KillContainer(dc.KillContainerOptions{
+			ID: container.ID,
+		}); err != nil {
+			return 0, err
+		}
+		code, err = p.waitContainer(container.ID, 0)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	updated, err := p.Client.InspectContainer(container.ID)
+	if err != nil {
+		return code, err
+	}
+	p.updateContainer(updated)
+
+	return code, nil
+}
+
+// RunJob runs a container to completion and returns its exit code
+// and output. A non-zero exit code is not reported as an error.
+// If `timeout` is greater than zero and the container doesn't finish
+// in time, it's killed and `ErrTimeout` is returned along with
+// the output collected so far. The container is removed afterwards,
+// so `AutoRemove` must not be set in `opts`.
+func (p *Pool) RunJob(
+	opts dc.CreateContainerOptions, timeout time.Duration,
+) (*JobResult, error) {
+	container, err := p.RunContainerWithOpts(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer p.PurgeContainer(container)
+
+	code, errWait := p.waitContainer(container.ID, timeout)
+	if errWait == ErrTimeout {
+		p.Client.KillContainer(dc.KillContainerOptions{ID: container.ID})
+	} else if errWait != nil {
+		return nil, errWait
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := p.Client.Logs(dc.LogsOptions{
+		Container:    container.ID,
+		OutputStream: &stdout,
+		ErrorStream:  &stderr,
+		Stdout:       true,
+		Stderr:       true,
+	}); err != nil {
+		return nil, err
+	}
+
+	return &JobResult{
+		ExitCode: code,
+		Stdout:   stdout.Bytes(),
+		Stderr:   stderr.Bytes(),
+	}, errWait
+}
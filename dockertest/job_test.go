@@ -0,0 +1,114 @@
+package dockertest
+
+import (
+	"testing"
+	"time"
+
+	dc "github.com/fsouza/go-dockerclient"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func jobOpts(cmd ...string) dc.CreateContainerOptions {
+	return dc.CreateContainerOptions{
+		Config: &dc.Config{
+			Image: testLocalImage,
+			Cmd:   append([]string{"--"}, cmd...),
+		},
+	}
+}
+
+func TestRunJob(t *testing.T) {
+	Convey("Given a new pool", t, func() {
+		pool, err := NewPool("")
+		So(err, ShouldBeNil)
+
+		Convey("When running a job to completion", func() {
+			res, err := pool.RunJob(
+				jobOpts("sh", "-c", "echo out; echo err >&2; exit 3"),
+				10*time.Second,
+			)
+
+			Convey("Should return exit code and output", func() {
+				So(err, ShouldBeNil)
+				So(res.ExitCode, ShouldEqual, 3)
+				So(string(res.Stdout), ShouldEqual, "out\n")
+				So(string(res.Stderr), ShouldEqual, "err\n")
+			})
+
+			Convey("Should remove the container", func() {
+				So(len(pool.Containers), ShouldEqual, 0)
+			})
+		})
+
+		Convey("When a job doesn't finish in time", func() {
+			_, err := pool.RunJob(jobOpts("sleep", "30"), time.Second)
+
+			Convey("Should report a timeout", func() {
+				So(err, ShouldEqual, ErrTimeout)
+				So(len(pool.Containers), ShouldEqual, 0)
+			})
+		})
+
+		Reset(func() {
+			pool.PurgeAll()
+		})
+	})
+}
+
+func TestStopContainer(t *testing.T) {
+	Convey("Given a new pool", t, func() {
+		pool, err := NewPool("")
+		So(err, ShouldBeNil)
+
+		Convey("When stopping a container handling SIGTERM", func() {
+			container, err := pool.RunContainerWithOpts(jobOpts(
+				"sh", "-c", "trap 'exit 7' TERM; while true; do sleep 0.1; done",
+			))
+			So(err, ShouldBeNil)
+
+			code, err := pool.StopContainer(container, dc.SIGTERM, 10*time.Second)
+
+			Convey("Should return its exit code", func() {
+				So(err, ShouldBeNil)
+				So(code, ShouldEqual, 7)
+				So(pool.Containers[0].State.Running, ShouldBeFalse)
+			})
+
+			Convey("Should be able to purge it", func() {
+				So(pool.PurgeAll(), ShouldBeNil)
+			})
+		})
+
+		Convey("When stopping a container ignoring SIGTERM", func() {
+			container, err := pool.RunContainerWithOpts(jobOpts("sleep", "30"))
+			So(err, ShouldBeNil)
+
+			code, err := pool.StopContainer(container, dc.SIGTERM, time.Second)
+
+			Convey("Should kill it after the grace period", func() {
+				So(err, ShouldBeNil)
+				So(code, ShouldEqual, 137)
+			})
+		})
+
+		Convey("When stopping a container without a grace period", func() {
+			container, err := pool.RunContainerWithOpts(jobOpts(
+				"sh", "-c", "trap 'exit 7' TERM; while true; do sleep 0.1; done",
+			))
+			So(err, ShouldBeNil)
+
+			start := time.Now()
+			code, err := pool.StopContainer(container, dc.SIGTERM, 0)
+
+			Convey("Should kill it immediately", func() {
+				So(err, ShouldBeNil)
+				So(code, ShouldEqual, 137)
+				So(time.Since(start), ShouldBeLessThan, 5*time.Second)
+			})
+		})
+
+		Reset(func() {
+			pool.PurgeAll()
+		})
+	})
+}
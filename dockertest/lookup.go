@@ -0,0 +1,232 @@
+package dockertest
+
+import (
+	"strings"
+	"sync"
+
+	dc "github.com/fsouza/go-dockerclient"
+)
+
+// ContainerFilter selects tracked containers. Empty fields match
+// every container.
+type ContainerFilter struct {
+	// Labels must all be present on a container. An empty value
+	// matches any value of the label.
+	Labels map[string]string
+
+	// Image is an image name, for example "postgres:9.6".
+	Image string
+}
+
+// copyContainer returns a copy of the container which can be modified
+// without affecting the pool. Labels, env, commands, ports, binds,
+// mounts and networks are copied; other nested values are shared.
+func copyContainer(container *dc.Container) *dc.Container {
+	c := *container
+	if container.Config != nil {
+		config := *container.Config
+		config.Labels = make(map[string]string, len(container.Config.Labels))
+		for k, v := range container.Config.Labels {
+			config.Labels[k] = v
+		}
+		config.Env = copyStrings(config.Env)
+		config.Cmd = copyStrings(config.Cmd)
+		config.Entrypoint = copyStrings(config.Entrypoint)
+		if config.ExposedPorts != nil {
+			config.ExposedPorts = make(map[dc.Port]struct{}, len(container.Config.ExposedPorts))
+			for port := range container.Config.ExposedPorts {
+				config.ExposedPorts[port] = struct{}{}
+			}
+		}
+		c.Config = &config
+	}
+	if container.HostConfig != nil {
+		hostConfig := *container.HostConfig
+		hostConfig.Binds = copyStrings(hostConfig.Binds)
+		hostConfig.PortBindings = copyPortBindings(hostConfig.PortBindings)
+		if hostConfig.Mounts != nil {
+			hostConfig.Mounts = append([]dc.HostMount(nil), hostConfig.Mounts...)
+		}
+		c.HostConfig = &hostConfig
+	}
+	if container.NetworkSettings != nil {
+		settings := *container.NetworkSettings
+		settings.Ports = copyPortBindings(settings.Ports)
+		if settings.Networks != nil {
+			settings.Networks = make(map[string]dc.ContainerNetwork, len(container.NetworkSettings.Networks))
+			for name, network := range container.NetworkSettings.Networks {
+				network.Aliases = copyStrings(network.Aliases)
+				settings.Networks[name] = network
+			}
+		}
+		c.NetworkSettings = &settings
+	}
+	if container.Mounts != nil {
+		c.Mounts = append([]dc.Mount(nil), container.Mounts...)
+	}
+
+	return &c
+}
+
+func copyStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	return append([]string(nil), s...)
+}
+
+func copyPortBindings(ports map[dc.Port][]dc.PortBinding) map[dc.Port][]dc.PortBinding {
+	if ports == nil {
+		return nil
+	}
+	c := make(map[dc.Port][]dc.PortBinding, len(ports))
+	for port, bindings := range ports {
+		c[port] = append([]dc.PortBinding(nil), bindings...)
+	}
+	return c
+}
+
+// GetContainer returns a copy of a tracked container by name, ID
+// or a unique ID prefix. The name can be given with or without
+// the leading `/`, which is how go-dockerclient stores names.
+// Sub-pools also match names without their prefix and look up
+// containers of parent pools.
+func (p *Pool) GetContainer(nameOrID string) (*dc.Container, bool) {
+	if nameOrID == "" {
+		return nil, false
+	}
+
+	if container, ok := p.getContainer(nameOrID); ok {
+		return container, true
+	}
+	if p.name != "" {
+		scoped := p.scopedName(strings.TrimPrefix(nameOrID, "/"))
+		if container, ok := p.getContainer(scoped); ok {
+			return container, true
+		}
+	}
+	if p.parent != nil {
+		return p.parent.GetContainer(nameOrID)
+	}
+
+	return nil, false
+}
+
+// getContainer looks up a container tracked by the pool. Exact names
+// and IDs take precedence over ID prefixes, so a name isn't hidden
+// by IDs starting with it.
+func (p *Pool) getContainer(nameOrID string) (*dc.Container, bool) {
+	name := "/" + strings.TrimPrefix(nameOrID, "/")
+
+	p.rw.RLock()
+	defer p.rw.RUnlock()
+
+	for _, container := range p.Containers {
+		if container.Name == name || container.ID == nameOrID {
+			return copyContainer(container), true
+		}
+	}
+
+	var found *dc.Container
+	for _, container := range p.Containers {
+		if strings.HasPrefix(container.ID, nameOrID) {
+			if found != nil {
+				// Ambiguous prefix.
+				return nil, false
+			}
+			found = container
+		}
+	}
+
+	if found == nil {
+		return nil, false
+	}
+
+	return copyContainer(found), true
+}
+
+// Match returns true if the container matches the filter.
+func (f ContainerFilter) Match(container *dc.Container) bool {
+	if container.Config == nil {
+		return len(f.Labels) == 0 && f.Image == ""
+	}
+
+	if f.Image != "" && container.Config.Image != f.Image {
+		return false
+	}
+
+	for k, v := range f.Labels {
+		value, ok := container.Config.Labels[k]
+		if !ok || v != "" && v != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// updateContainer replaces a tracked container having the same ID.
+func (p *Pool) updateContainer(container *dc.Container) {
+	p.rw.Lock()
+	for i, c := range p.Containers {
+		if c.ID == container.ID {
+			p.Containers[i] = container
+		}
+	}
+	p.rw.Unlock()
+}
+
+// FindContainers returns copies of tracked containers matching the filter.
+func (p *Pool) FindContainers(filter ContainerFilter) ContainerList {
+	p.rw.RLock()
+	defer p.rw.RUnlock()
+
+	var found ContainerList
+	for _, container := range p.Containers {
+		if filter.Match(container) {
+			found = append(found, copyContainer(container))
+		}
+	}
+
+	return found
+}
+
+// Refresh inspects tracked containers again, so that their status and
+// ports are up-to-date. Containers which no longer exist are removed
+// from the pool.
+func (p *Pool) Refresh() error {
+	p.rw.RLock()
+	containers := append(ContainerList(nil), p.Containers...)
+	p.rw.RUnlock()
+
+	var wg sync.WaitGroup
+	errCh := make(chan error, len(containers))
+
+	for _, c := range containers {
+		wg.Add(1)
+		go func(container *dc.Container) {
+			defer wg.Done()
+
+			updated, err := p.Client.InspectContainer(container.ID)
+			if _, ok := err.(*dc.NoSuchContainer); ok {
+				p.rw.Lock()
+				p.Containers = p.Containers.Remove(container)
+				p.rw.Unlock()
+				return
+			} else if err != nil {
+				errCh <- err
+				return
+			}
+
+			p.updateContainer(updated)
+		}(c)
+	}
+
+	wg.Wait()
+	close(errCh)
+	if err := <-errCh; err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+package dockertest
+
+import (
+	"testing"
+
+	dc "github.com/fsouza/go-dockerclient"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGetContainer(t *testing.T) {
+	Convey("Given a pool with tracked containers", t, func() {
+		pool := &Pool{Containers: ContainerList{
+			{ID: "abc123", Name: "/db", Config: &dc.Config{
+				Image:  "postgres:9.6",
+				Labels: map[string]string{"app": "test", "tier": "db"},
+			}},
+			{ID: "abd456", Name: "/cache", Config: &dc.Config{
+				Image:  "redis:3",
+				Labels: map[string]string{"app": "test"},
+			}},
+			{ID: "xyz789", Name: ""},
+			{ID: "def000", Name: "/ab"},
+		}}
+
+		Convey("Should find containers by name with or without a slash", func() {
+			c, ok := pool.GetContainer("db")
+			So(ok, ShouldBeTrue)
+			So(c.ID, ShouldEqual, "abc123")
+
+			c, ok = pool.GetContainer("/cache")
+			So(ok, ShouldBeTrue)
+			So(c.ID, ShouldEqual, "abd456")
+		})
+
+		Convey("Should find containers by ID and unique ID prefix", func() {
+			c, ok := pool.GetContainer("xyz789")
+			So(ok, ShouldBeTrue)
+			So(c.ID, ShouldEqual, "xyz789")
+
+			c, ok = pool.GetContainer("abd")
+			So(ok, ShouldBeTrue)
+			So(c.ID, ShouldEqual, "abd456")
+
+			_, ok = pool.GetContainer("a")
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("Should prefer exact names to ID prefixes", func() {
+			c, ok := pool.GetContainer("ab")
+			So(ok, ShouldBeTrue)
+			So(c.ID, ShouldEqual, "def000")
+		})
+
+		Convey("Should not panic on empty names", func() {
+			_, ok := pool.GetContainer("")
+			So(ok, ShouldBeFalse)
+			_, ok = pool.GetContainer("missing")
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("Should return copies", func() {
+			c, _ := pool.GetContainer("db")
+			c.Config.Labels["app"] = "changed"
+			c.Name = "/changed"
+			So(pool.Containers[0].Config.Labels["app"], ShouldEqual, "test")
+			So(pool.Containers[0].Name, ShouldEqual, "/db")
+		})
+
+		Convey("Should copy nested slices and maps", func() {
+			pool.Containers[0].Config.Env = []string{"A=1"}
+			pool.Containers[0].HostConfig = &dc.HostConfig{
+				Binds:        []string{"data:/data"},
+				PortBindings: map[dc.Port][]dc.PortBinding{"5432/tcp": {{HostPort: "5432"}}},
+			}
+			pool.Containers[0].NetworkSettings = &dc.NetworkSettings{
+				Networks: map[string]dc.ContainerNetwork{"net": {Aliases: []string{"db"}}},
+			}
+			pool.Containers[0].Mounts = []dc.Mount{{Name: "data"}}
+
+			c, _ := pool.GetContainer("db")
+			c.Config.Env[0] = "A=2"
+			c.HostConfig.Binds[0] = "other:/data"
+			c.HostConfig.PortBindings["5432/tcp"][0].HostPort = "1"
+			c.NetworkSettings.Networks["net"].Aliases[0] = "changed"
+			c.Mounts[0].Name = "other"
+
+			original := pool.Containers[0]
+			So(original.Config.Env, ShouldResemble, []string{"A=1"})
+			So(original.HostConfig.Binds, ShouldResemble, []string{"data:/data"})
+			So(original.HostConfig.PortBindings["5432/tcp"][0].HostPort, ShouldEqual, "5432")
+			So(original.NetworkSettings.Networks["net"].Aliases, ShouldResemble, []string{"db"})
+			So(original.Mounts[0].Name, ShouldEqual, "data")
+		})
+
+		Convey("Should find containers by labels and image", func() {
+			found := pool.FindContainers(ContainerFilter{
+				Labels: map[string]string{"app": "test"},
+			})
+			So(found, ShouldHaveLength, 2)
+
+			found = pool.FindContainers(ContainerFilter{
+				Labels: map[string]string{"app": "test", "tier": ""},
+			})
+			So(found, ShouldHaveLength, 1)
+			So(found[0].ID, ShouldEqual, "abc123")
+
+			found = pool.FindContainers(ContainerFilter{Image: "redis:3"})
+			So(found, ShouldHaveLength, 1)
+			So(found[0].ID, ShouldEqual, "abd456")
+
+			So(pool.FindContainers(ContainerFilter{}), ShouldHaveLength, 4)
+		})
+	})
+}
+
+func TestRefresh(t *testing.T) {
+	Convey("Given a pool with a running container", t, func() {
+		pool, err := NewPool("")
+		So(err, ShouldBeNil)
+
+		container, err := pool.RunContainer(testLocalImage, nil, false)
+		So(err, ShouldBeNil)
+
+		Convey("When the container is stopped outside of the pool", func() {
+			err := pool.Client.KillContainer(dc.KillContainerOptions{ID: container.ID})
+			So(err, ShouldBeNil)
+			So(pool.Refresh(), ShouldBeNil)
+
+			Convey("Should update its state", func() {
+				c, ok := pool.GetContainer(container.ID)
+				So(ok, ShouldBeTrue)
+				So(c.State.Running, ShouldBeFalse)
+			})
+		})
+
+		Convey("When the container is removed outside of the pool", func() {
+			err := pool.Client.RemoveContainer(dc.RemoveContainerOptions{
+				ID:    container.ID,
+				Force: true,
+			})
+			So(err, ShouldBeNil)
+			So(pool.Refresh(), ShouldBeNil)
+
+			Convey("Should stop tracking it", func() {
+				So(pool.Containers, ShouldBeEmpty)
+			})
+		})
+
+		Reset(func() {
+			pool.PurgeAll()
+		})
+	})
+}
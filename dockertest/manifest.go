@@ -0,0 +1,325 @@
+package dockertest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/adambabik/go-collections/internal/yamlutil"
+	dc "github.com/fsouza/go-dockerclient"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// LabelService is set on containers started from a manifest
+// and contains a service name.
+const LabelService = "dockertest.service"
+
+// defaultReadyTimeout is used when a readiness check has no timeout.
+const defaultReadyTimeout = 30 * time.Second
+
+type (
+	// Manifest describes a test environment. It can be read from
+	// a JSON or YAML file with `LoadManifest()`.
+	Manifest struct {
+		// Networks and Volumes are created before services start.
+		// Their docker names are suffixed with the pool session.
+		Networks []string            `json:"networks"`
+		Volumes  []string            `json:"volumes"`
+		Services map[string]*Service `json:"services"`
+
+		// dir is used to resolve relative paths.
+		dir string
+	}
+
+	// Service describes a single container in a manifest.
+	Service struct {
+		Image string   `json:"image"`
+		Env   Env      `json:"env"`
+		Cmd   []string `json:"cmd"`
+
+		// Ports are exposed and published on random host ports,
+		// for example "5432/tcp".
+		Ports []string `json:"ports"`
+
+		// Networks are manifest networks. The service name is used
+		// as an alias in each of them.
+		Networks []string `json:"networks"`
+
+		// Volumes are in format SOURCE:TARGET[:ro]. SOURCE is either
+		// a manifest volume or a host path.
+		Volumes []string `json:"volumes"`
+
+		// Files maps a path in the container to a host file which
+		// is copied before the container starts.
+		Files map[string]string `json:"files"`
+
+		Ready *Readiness `json:"ready"`
+	}
+
+	// Readiness describes how to check if a service is ready.
+	// Only one of Port, Exec or Log should be set.
+	Readiness struct {
+		Port    string   `json:"port"`
+		Exec    []string `json:"exec"`
+		Log     string   `json:"log"`
+		Timeout Duration `json:"timeout"`
+	}
+
+	// Duration is a `time.Duration` read from strings like "30s".
+	Duration time.Duration
+
+	// Environment is a test environment started from a manifest.
+	Environment struct {
+		Services map[string]*Resource
+
+		// Addrs maps a service name to its published ports
+		// and their local host:port addresses.
+		Addrs map[string]map[string]string
+
+		Networks map[string]*dc.Network
+		Volumes  map[string]*dc.Volume
+	}
+)
+
+// UnmarshalJSON parses a duration string.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+
+	return nil
+}
+
+// LoadManifest reads a manifest from a file. Files with .yml or .yaml
+// extension are read as YAML, other as JSON. Relative host paths
+// in the manifest are resolved against the file directory.
+func LoadManifest(filename string) (*Manifest, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yml", ".yaml":
+		if data, err = yamlToJSON(data); err != nil {
+			return nil, fmt.Errorf("%s: %v", filename, err)
+		}
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	m.dir = filepath.Dir(filename)
+
+	if err := m.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+
+	return &m, nil
+}
+
+// yamlToJSON converts YAML to JSON so that a single set of json tags
+// is used for both formats.
+func yamlToJSON(data []byte) ([]byte, error) {
+	var v interface{}
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+
+	v, err := yamlutil.Convert(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(v)
+}
+
+func (m *Manifest) validate() error {
+	if len(m.Services) == 0 {
+		return errors.New("no services")
+	}
+
+	networks := stringSet(m.Networks)
+	for _, name := range m.serviceNames() {
+		s := m.Services[name]
+		if s == nil || s.Image == "" {
+			return fmt.Errorf("service %s: missing image", name)
+		}
+		for _, n := range s.Networks {
+			if !networks[n] {
+				return fmt.Errorf("service %s: unknown network %s", name, n)
+			}
+		}
+		for _, v := range s.Volumes {
+			if len(strings.Split(v, ":")) < 2 {
+				return fmt.Errorf("service %s: invalid volume %s", name, v)
+			}
+		}
+	}
+
+	return nil
+}
+
+func stringSet(list []string) map[string]bool {
+	set := make(map[string]bool, len(list))
+	for _, s := range list {
+		set[s] = true
+	}
+	return set
+}
+
+func (m *Manifest) serviceNames() []string {
+	names := make([]string, 0, len(m.Services))
+	for name := range m.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Images returns a sorted list of unique images used by services.
+func (m *Manifest) Images() []string {
+	seen := make(map[string]bool)
+	images := make([]string, 0, len(m.Services))
+	for _, s := range m.Services {
+		if !seen[s.Image] {
+			seen[s.Image] = true
+			images = append(images, s.Image)
+		}
+	}
+	sort.Strings(images)
+
+	return images
+}
+
+// hostPath resolves a path relative to the manifest file.
+func (m *Manifest) hostPath(path string) string {
+	if filepath.IsAbs(path) || m.dir == "" {
+		return path
+	}
+	return filepath.Join(m.dir, path)
+}
+
+// builder returns a container builder for the service. `names` maps
+// manifest networks and volumes to their docker names.
+func (m *Manifest) builder(name string, names map[string]string) *ContainerBuilder {
+	s := m.Services[name]
+
+	b := NewContainer(s.Image).
+		WithEnv(s.Env...).
+		WithPort(s.Ports...).
+		WithLabel(LabelService, name)
+	if len(s.Cmd) > 0 {
+		b.WithCmd(s.Cmd...)
+	}
+
+	for _, n := range s.Networks {
+		b.WithNetwork(names[n], name)
+	}
+
+	volumes := stringSet(m.Volumes)
+	for _, v := range s.Volumes {
+		parts := strings.Split(v, ":")
+		var mount Mount
+		if volumes[parts[0]] {
+			mount = VolumeMount(names[parts[0]], parts[1])
+		} else {
+			mount = BindMount(m.hostPath(parts[0]), parts[1])
+		}
+		mount.ReadOnly = len(parts) > 2 && parts[2] == "ro"
+		b.WithMount(mount)
+	}
+
+	targets := make([]string, 0, len(s.Files))
+	for target := range s.Files {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+	for _, target := range targets {
+		b.WithFile(m.hostPath(s.Files[target]), target)
+	}
+
+	if s.Ready != nil {
+		timeout := time.Duration(s.Ready.Timeout)
+		if timeout == 0 {
+			timeout = defaultReadyTimeout
+		}
+
+		switch {
+		case s.Ready.Port != "":
+			b.WithWait(timeout, WaitForPort(s.Ready.Port))
+		case len(s.Ready.Exec) > 0:
+			b.WithWait(timeout, WaitForExec(s.Ready.Exec...))
+		case s.Ready.Log != "":
+			b.WithWait(timeout, WaitForLog(s.Ready.Log))
+		}
+	}
+
+	return b
+}
+
+// StartManifest creates networks and volumes, pulls missing images and
+// runs all services from the manifest in the name order, waiting for
+// each to be ready. If any step fails, the returned environment
+// contains resources started so far.
+func (p *Pool) StartManifest(m *Manifest) (*Environment, error) {
+	env := &Environment{
+		Services: make(map[string]*Resource, len(m.Services)),
+		Addrs:    make(map[string]map[string]string, len(m.Services)),
+		Networks: make(map[string]*dc.Network, len(m.Networks)),
+		Volumes:  make(map[string]*dc.Volume, len(m.Volumes)),
+	}
+	names := make(map[string]string, len(m.Networks)+len(m.Volumes))
+
+	for _, name := range m.Networks {
+		net, err := p.CreateNetwork(name + "_" + p.Session)
+		if err != nil {
+			return env, err
+		}
+		env.Networks[name] = net
+		names[name] = net.Name
+	}
+
+	for _, name := range m.Volumes {
+		vol, err := p.CreateVolume(name + "_" + p.Session)
+		if err != nil {
+			return env, err
+		}
+		env.Volumes[name] = vol
+		names[name] = vol.Name
+	}
+
+	for _, name := range m.serviceNames() {
+		r, err := m.builder(name, names).Run(p)
+		if err != nil {
+			return env, fmt.Errorf("service %s: %v", name, err)
+		}
+
+		env.Services[name] = r
+		env.Addrs[name] = make(map[string]string)
+		for _, port := range m.Services[name].Ports {
+			env.Addrs[name][port] = r.Addr(port)
+		}
+	}
+
+	return env, nil
+}
+
+// Addr returns a local host:port address of the service port.
+func (e *Environment) Addr(service, port string) string {
+	return e.Addrs[service][port]
+}
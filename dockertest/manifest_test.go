@@ -0,0 +1,98 @@
+package dockertest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLoadManifest(t *testing.T) {
+	Convey("Given a JSON manifest file", t, func() {
+		m, err := LoadManifest("testdata/manifest.json")
+		So(err, ShouldBeNil)
+
+		Convey("Should read services", func() {
+			So(len(m.Services), ShouldEqual, 3)
+			So(m.Services["db"].Image, ShouldEqual, "postgres:9.6")
+			So(m.Services["db"].Env, ShouldResemble, Env{"POSTGRES_PASSWORD=secret"})
+			So(m.Services["cache-replica"].Cmd, ShouldHaveLength, 4)
+		})
+
+		Convey("Should return unique images", func() {
+			So(m.Images(), ShouldResemble, []string{"postgres:9.6", "redis:3"})
+		})
+	})
+
+	Convey("Given a YAML manifest file", t, func() {
+		m, err := LoadManifest("testdata/manifest.yml")
+		So(err, ShouldBeNil)
+
+		Convey("Should read networks, volumes and services", func() {
+			So(m.Networks, ShouldResemble, []string{"backend"})
+			So(m.Volumes, ShouldResemble, []string{"pgdata"})
+
+			db := m.Services["db"]
+			So(db.Ports, ShouldResemble, []string{"5432/tcp"})
+			So(db.Files["/docker-entrypoint-initdb.d/schema.sql"], ShouldEqual, "init/schema.sql")
+			So(db.Ready.Exec, ShouldResemble, []string{"pg_isready", "-U", "postgres"})
+			So(time.Duration(db.Ready.Timeout), ShouldEqual, time.Minute)
+			So(m.Services["cache"].Ready.Port, ShouldEqual, "6379/tcp")
+		})
+
+		Convey("Should build container options", func() {
+			names := map[string]string{"backend": "backend_s", "pgdata": "pgdata_s"}
+			b := m.builder("db", names)
+			opts := b.Options()
+
+			So(opts.Config.Labels[LabelService], ShouldEqual, "db")
+			So(opts.NetworkingConfig.EndpointsConfig["backend_s"].Aliases,
+				ShouldResemble, []string{"db"})
+			So(opts.HostConfig.Binds, ShouldResemble, []string{
+				"pgdata_s:/var/lib/postgresql/data",
+				filepath.Join("testdata", "init") + ":/init:ro",
+			})
+			So(b.files, ShouldResemble, []fileCopy{{
+				source: filepath.Join("testdata", "init", "schema.sql"),
+				target: "/docker-entrypoint-initdb.d/schema.sql",
+			}})
+			So(b.wait, ShouldNotBeNil)
+			So(b.waitTimeout, ShouldEqual, time.Minute)
+		})
+	})
+
+	Convey("Given an invalid manifest", t, func() {
+		dir, err := ioutil.TempDir("", "manifest")
+		So(err, ShouldBeNil)
+		filename := filepath.Join(dir, "manifest.yaml")
+
+		Convey("Should report a service without image", func() {
+			ioutil.WriteFile(filename, []byte("services:\n  db: {}\n"), 0644)
+			_, err := LoadManifest(filename)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "service db: missing image")
+		})
+
+		Convey("Should report an unknown network", func() {
+			ioutil.WriteFile(filename, []byte("services:\n  db:\n    image: a\n    networks: [x]\n"), 0644)
+			_, err := LoadManifest(filename)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "unknown network x")
+		})
+
+		Reset(func() {
+			os.RemoveAll(dir)
+		})
+	})
+
+	Convey("Given a not existing manifest file", t, func() {
+		_, err := LoadManifest("testdata/missing.json")
+
+		Convey("Should report an error", func() {
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
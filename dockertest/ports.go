@@ -0,0 +1,214 @@
+package dockertest
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	dc "github.com/fsouza/go-dockerclient"
+)
+
+type (
+	// RunOption customizes a container started by `RunContainer()`.
+	// It's implemented by `Mount`, `Mounts` and `PortMapping`.
+	RunOption interface {
+		apply(opts *dc.CreateContainerOptions)
+	}
+
+	// PortMapping publishes a container port on a host port.
+	PortMapping struct {
+		// ContainerPort is a port with an optional protocol, for example
+		// "5432", "5432/tcp" or "53/udp". The protocol defaults to tcp.
+		ContainerPort string
+
+		// HostIP is an interface to bind to, for example "127.0.0.1"
+		// or "::1". All interfaces are used if it's empty.
+		HostIP string
+
+		// HostPort is a host port. A random port is used if it's empty.
+		HostPort string
+	}
+
+	// PortConflictError is returned when a host port is already used.
+	PortConflictError struct {
+		HostIP   string
+		HostPort string
+		Proto    string
+		Reason   string
+	}
+)
+
+func (e *PortConflictError) Error() string {
+	return fmt.Sprintf("dockertest: host port %s/%s is already used: %s",
+		net.JoinHostPort(e.HostIP, e.HostPort), e.Proto, e.Reason)
+}
+
+// normalizePort appends the default "/tcp" protocol if it's missing.
+func normalizePort(port string) dc.Port {
+	if !strings.Contains(port, "/") {
+		port += "/tcp"
+	}
+	return dc.Port(port)
+}
+
+func (m Mount) apply(opts *dc.CreateContainerOptions) {
+	Mounts{m}.Apply(opts.HostConfig)
+}
+
+func (m Mounts) apply(opts *dc.CreateContainerOptions) {
+	m.Apply(opts.HostConfig)
+}
+
+func (m PortMapping) apply(opts *dc.CreateContainerOptions) {
+	port := normalizePort(m.ContainerPort)
+
+	if opts.Config.ExposedPorts == nil {
+		opts.Config.ExposedPorts = make(map[dc.Port]struct{})
+	}
+	opts.Config.ExposedPorts[port] = struct{}{}
+
+	if opts.HostConfig.PortBindings == nil {
+		opts.HostConfig.PortBindings = make(map[dc.Port][]dc.PortBinding)
+	}
+	opts.HostConfig.PortBindings[port] = append(
+		opts.HostConfig.PortBindings[port],
+		dc.PortBinding{HostIP: m.HostIP, HostPort: m.HostPort},
+	)
+}
+
+// isWildcardIP returns true for addresses binding all interfaces.
+func isWildcardIP(ip string) bool {
+	return ip == "" || ip == "0.0.0.0" || ip == "::"
+}
+
+// bindingsConflict returns true if two bindings of the same protocol
+// can't be used at the same time.
+func bindingsConflict(a, b dc.PortBinding) bool {
+	if a.HostPort == "" || a.HostPort != b.HostPort {
+		return false
+	}
+	return isWildcardIP(a.HostIP) || isWildcardIP(b.HostIP) || a.HostIP == b.HostIP
+}
+
+type hostBinding struct {
+	proto   string
+	binding dc.PortBinding
+}
+
+func hostBindings(hostConfig *dc.HostConfig) []hostBinding {
+	if hostConfig == nil {
+		return nil
+	}
+
+	var bindings []hostBinding
+	for port, list := range hostConfig.PortBindings {
+		for _, b := range list {
+			bindings = append(bindings, hostBinding{port.Proto(), b})
+		}
+	}
+	return bindings
+}
+
+// checkPortConflicts returns `*PortConflictError` if explicit host
+// ports in `hostConfig` collide with each other or with containers
+// tracked by the root pool or any of its sub-pools, which share
+// the host.
+func (p *Pool) checkPortConflicts(hostConfig *dc.HostConfig) error {
+	requested := hostBindings(hostConfig)
+
+	for i, a := range requested {
+		for _, b := range requested[i+1:] {
+			if a.proto == b.proto && bindingsConflict(a.binding, b.binding) {
+				return &PortConflictError{
+					HostIP:   a.binding.HostIP,
+					HostPort: a.binding.HostPort,
+					Proto:    a.proto,
+					Reason:   "requested more than once",
+				}
+			}
+		}
+	}
+
+	for _, container := range p.root().allContainers() {
+		for _, b := range hostBindings(container.HostConfig) {
+			for _, a := range requested {
+				if a.proto == b.proto && bindingsConflict(a.binding, b.binding) {
+					return &PortConflictError{
+						HostIP:   a.binding.HostIP,
+						HostPort: a.binding.HostPort,
+						Proto:    a.proto,
+						Reason:   "used by container " + containerName(container),
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// portConflictFromDocker converts a docker error about an allocated
+// port to `*PortConflictError`.
+func portConflictFromDocker(err error) error {
+	msg := err.Error()
+	if !strings.Contains(msg, "port is already allocated") &&
+		!strings.Contains(msg, "address already in use") {
+		return err
+	}
+
+	return &PortConflictError{Reason: msg}
+}
+
+// GetHostPort returns a host address and port bound to the container
+// port `id`, for example "53/udp". IPv4 bindings are preferred unless
+// `ipv6` is true. A wildcard address is returned as a loopback address.
+func GetHostPort(container *dc.Container, id string, ipv6 bool) (string, string, bool) {
+	if container.NetworkSettings == nil {
+		return "", "", false
+	}
+
+	bindings := container.NetworkSettings.Ports[normalizePort(id)]
+	if len(bindings) == 0 {
+		return "", "", false
+	}
+
+	binding := bindings[0]
+	for _, b := range bindings {
+		if isIPv6(b.HostIP) == ipv6 {
+			binding = b
+			break
+		}
+	}
+
+	host := binding.HostIP
+	switch {
+	case host == "" || host == "0.0.0.0":
+		host = "127.0.0.1"
+	case host == "::":
+		host = "::1"
+	}
+
+	return host, binding.HostPort, true
+}
+
+func isIPv6(ip string) bool {
+	return strings.Contains(ip, ":")
+}
+
+// GetAddr returns an address of the container port `id` in format
+// host:port or scheme://host:port if `scheme` is not empty. IPv6 hosts
+// are put in brackets. An empty string is returned if the port
+// is not published.
+func GetAddr(container *dc.Container, id string, scheme string) string {
+	host, port, ok := GetHostPort(container, id, false)
+	if !ok {
+		return ""
+	}
+
+	addr := net.JoinHostPort(host, port)
+	if scheme != "" {
+		addr = scheme + "://" + addr
+	}
+
+	return addr
+}
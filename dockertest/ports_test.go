@@ -0,0 +1,131 @@
+package dockertest
+
+import (
+	"errors"
+	"testing"
+
+	dc "github.com/fsouza/go-dockerclient"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPortMappingApply(t *testing.T) {
+	Convey("Given tcp and udp port mappings", t, func() {
+		opts := dc.CreateContainerOptions{
+			Config:     &dc.Config{},
+			HostConfig: &dc.HostConfig{},
+		}
+		PortMapping{ContainerPort: "5432", HostIP: "127.0.0.1", HostPort: "15432"}.apply(&opts)
+		PortMapping{ContainerPort: "53/udp"}.apply(&opts)
+
+		Convey("Should expose and bind them", func() {
+			So(opts.Config.ExposedPorts, ShouldResemble, map[dc.Port]struct{}{
+				"5432/tcp": {},
+				"53/udp":   {},
+			})
+			So(opts.HostConfig.PortBindings, ShouldResemble, map[dc.Port][]dc.PortBinding{
+				"5432/tcp": {{HostIP: "127.0.0.1", HostPort: "15432"}},
+				"53/udp":   {{}},
+			})
+		})
+	})
+}
+
+func TestCheckPortConflicts(t *testing.T) {
+	Convey("Given a pool with a container bound to 8080/tcp", t, func() {
+		pool := &Pool{Containers: ContainerList{{
+			Name: "/web",
+			HostConfig: &dc.HostConfig{
+				PortBindings: map[dc.Port][]dc.PortBinding{
+					"80/tcp": {{HostIP: "127.0.0.1", HostPort: "8080"}},
+				},
+			},
+		}}}
+		bind := func(port string, bindings ...dc.PortBinding) *dc.HostConfig {
+			return &dc.HostConfig{
+				PortBindings: map[dc.Port][]dc.PortBinding{dc.Port(port): bindings},
+			}
+		}
+
+		Convey("Should allow random and unused host ports", func() {
+			So(pool.checkPortConflicts(bind("80/tcp", dc.PortBinding{})), ShouldBeNil)
+			So(pool.checkPortConflicts(bind("80/tcp", dc.PortBinding{HostPort: "8081"})), ShouldBeNil)
+		})
+
+		Convey("Should allow the same port with another protocol or interface", func() {
+			So(pool.checkPortConflicts(bind("80/udp", dc.PortBinding{HostPort: "8080"})), ShouldBeNil)
+			So(pool.checkPortConflicts(bind("80/tcp",
+				dc.PortBinding{HostIP: "127.0.0.2", HostPort: "8080"})), ShouldBeNil)
+		})
+
+		Convey("Should report a port used by the container", func() {
+			err := pool.checkPortConflicts(bind("80/tcp", dc.PortBinding{HostIP: "::", HostPort: "8080"}))
+			So(err, ShouldHaveSameTypeAs, &PortConflictError{})
+			So(err.Error(), ShouldEqual,
+				"dockertest: host port [::]:8080/tcp is already used: used by container web")
+		})
+
+		Convey("Should report a port used by a container of another sub-pool", func() {
+			child := pool.newChild("a")
+			pool.children = []*Pool{child, pool.newChild("b")}
+
+			err := pool.children[1].checkPortConflicts(bind("80/tcp", dc.PortBinding{HostPort: "8080"}))
+			So(err, ShouldHaveSameTypeAs, &PortConflictError{})
+
+			child.Containers, pool.Containers = pool.Containers, nil
+			err = pool.children[1].checkPortConflicts(bind("80/tcp", dc.PortBinding{HostPort: "8080"}))
+			So(err, ShouldHaveSameTypeAs, &PortConflictError{})
+		})
+
+		Convey("Should report a port requested twice", func() {
+			err := pool.checkPortConflicts(bind("90/tcp",
+				dc.PortBinding{HostPort: "9090"}, dc.PortBinding{HostIP: "127.0.0.1", HostPort: "9090"}))
+			So(err, ShouldHaveSameTypeAs, &PortConflictError{})
+			So(err.(*PortConflictError).Reason, ShouldEqual, "requested more than once")
+		})
+	})
+}
+
+func TestPortConflictFromDocker(t *testing.T) {
+	Convey("Should convert allocated port errors only", t, func() {
+		err := portConflictFromDocker(errors.New("Bind for 0.0.0.0:8080 failed: port is already allocated"))
+		So(err, ShouldHaveSameTypeAs, &PortConflictError{})
+
+		other := errors.New("no such image")
+		So(portConflictFromDocker(other), ShouldEqual, other)
+	})
+}
+
+func TestGetAddr(t *testing.T) {
+	Convey("Given a container with IPv4 and IPv6 bindings", t, func() {
+		container := &dc.Container{
+			NetworkSettings: &dc.NetworkSettings{
+				Ports: map[dc.Port][]dc.PortBinding{
+					"80/tcp": {
+						{HostIP: "::", HostPort: "32768"},
+						{HostIP: "0.0.0.0", HostPort: "32769"},
+					},
+					"53/udp": {{HostIP: "::1", HostPort: "32770"}},
+				},
+			},
+		}
+
+		Convey("Should prefer IPv4 and default to tcp", func() {
+			So(GetPort(container, "80"), ShouldEqual, "32769")
+			So(GetAddr(container, "80/tcp", ""), ShouldEqual, "127.0.0.1:32769")
+			So(GetServiceAddr(container, "80/tcp"), ShouldEqual, "http://127.0.0.1:32769")
+		})
+
+		Convey("Should return IPv6 addresses", func() {
+			host, port, ok := GetHostPort(container, "80/tcp", true)
+			So(ok, ShouldBeTrue)
+			So(host, ShouldEqual, "::1")
+			So(port, ShouldEqual, "32768")
+			So(GetAddr(container, "53/udp", "dns"), ShouldEqual, "dns://[::1]:32770")
+		})
+
+		Convey("Should return nothing for unpublished ports", func() {
+			So(GetPort(container, "8080/tcp"), ShouldBeEmpty)
+			So(GetAddr(container, "8080/tcp", "http"), ShouldBeEmpty)
+		})
+	})
+}
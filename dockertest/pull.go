@@ -0,0 +1,227 @@
+package dockertest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	dc "github.com/fsouza/go-dockerclient"
+)
+
+// Pull policies used by `PullImages()`.
+const (
+	PullIfNotPresent PullPolicy = "if-not-present"
+	PullAlways       PullPolicy = "always"
+	PullNever        PullPolicy = "never"
+)
+
+// defaultPullConcurrency is used when `PullOptions.Concurrency` is zero.
+const defaultPullConcurrency = 4
+
+type (
+	// PullPolicy decides when images are pulled.
+	PullPolicy string
+
+	// PullProgress is a progress message reported while pulling an image.
+	PullProgress struct {
+		Image string
+
+		// Layer is an ID of the layer the message is about. Messages
+		// about the whole image have the tag or an empty ID.
+		Layer  string
+		Status string
+
+		// Current and Total are bytes downloaded or extracted so far
+		// and in total. Both are zero if unknown.
+		Current int64
+		Total   int64
+	}
+
+	// PullOptions configure `PullImages()`.
+	PullOptions struct {
+		// Policy defaults to `PullIfNotPresent`.
+		Policy PullPolicy
+
+		// Platform is an image platform, for example "linux/arm64".
+		// It defaults to the docker daemon platform.
+		Platform string
+
+		// Concurrency limits how many images are pulled at once.
+		Concurrency int
+
+		// Progress is called for every progress message. It can be
+		// called concurrently for different images.
+		Progress func(PullProgress)
+	}
+
+	// jsonMessage is a progress message streamed by the docker API.
+	jsonMessage struct {
+		ID             string `json:"id"`
+		Status         string `json:"status"`
+		Error          string `json:"error"`
+		ProgressDetail struct {
+			Current int64 `json:"current"`
+			Total   int64 `json:"total"`
+		} `json:"progressDetail"`
+	}
+)
+
+// ParsePullPolicy returns the pull policy named `s`. Unknown names
+// are an error.
+func ParsePullPolicy(s string) (PullPolicy, error) {
+	switch policy := PullPolicy(s); policy {
+	case PullIfNotPresent, PullAlways, PullNever:
+		return policy, nil
+	}
+
+	return "", fmt.Errorf("dockertest: unknown pull policy %q", s)
+}
+
+// PullImages pulls images concurrently according to the pull policy.
+// It returns the first error.
+func (p *Pool) PullImages(images []string, opts PullOptions) error {
+	if opts.Policy != "" {
+		if _, err := ParsePullPolicy(string(opts.Policy)); err != nil {
+			return err
+		}
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultPullConcurrency
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	errCh := make(chan error, len(images))
+
+	for _, image := range images {
+		wg.Add(1)
+		go func(image string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			if err := p.pullWithPolicy(image, opts); err != nil {
+				errCh <- err
+			}
+		}(image)
+	}
+
+	wg.Wait()
+	close(errCh)
+	if err := <-errCh; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (p *Pool) pullWithPolicy(image string, opts PullOptions) error {
+	if opts.Policy == PullAlways {
+		return p.pullImage(image, opts)
+	}
+
+	img, err := p.Client.InspectImage(image)
+	if err == nil && matchPlatform(img, opts.Platform) {
+		p.recordImage(image, false)
+		return nil
+	}
+
+	if opts.Policy == PullNever {
+		if err == nil {
+			err = errors.New("dockertest: image " + image + " not present for " + opts.Platform)
+		}
+		return err
+	}
+
+	return p.pullImage(image, opts)
+}
+
+// matchPlatform returns true if the image is built for the platform
+// in format os/arch[/variant]. An empty platform matches every image.
+func matchPlatform(img *dc.Image, platform string) bool {
+	if platform == "" {
+		return true
+	}
+
+	parts := strings.Split(platform, "/")
+	if parts[0] != img.OS {
+		return false
+	}
+
+	return len(parts) < 2 || parts[1] == img.Architecture
+}
+
+// pullImage pulls the image, streaming progress to `opts.Progress`.
+func (p *Pool) pullImage(image string, opts PullOptions) error {
+	op := &Operation{Event: EventPull, Image: image}
+	if err := p.before(op); err != nil {
+		return err
+	}
+
+	imageName, tag := parseImageName(image)
+	pullOpts := dc.PullImageOptions{
+		Repository: imageName,
+		Tag:        tag,
+		Platform:   opts.Platform,
+	}
+
+	var done chan error
+	if opts.Progress != nil {
+		r, w := io.Pipe()
+		pullOpts.OutputStream = w
+		pullOpts.RawJSONStream = true
+
+		done = make(chan error, 1)
+		go func() {
+			done <- decodeProgress(r, image, opts.Progress)
+		}()
+		defer func() {
+			w.Close()
+			<-done
+		}()
+	}
+
+	err := p.Client.PullImage(pullOpts, dc.AuthConfiguration{})
+	if err == nil {
+		p.recordImage(image, true)
+	}
+
+	return p.after(op, err)
+}
+
+// decodeProgress reads JSON messages from `r` until it's closed.
+func decodeProgress(r io.ReadCloser, image string, progress func(PullProgress)) error {
+	defer r.Close()
+
+	dec := json.NewDecoder(r)
+	for {
+		var msg jsonMessage
+		if err := dec.Decode(&msg); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			// Drain the stream, so the writer isn't blocked.
+			io.Copy(ioutil.Discard, r)
+			return err
+		}
+
+		status := msg.Status
+		if msg.Error != "" {
+			status = msg.Error
+		}
+
+		progress(PullProgress{
+			Image:   image,
+			Layer:   msg.ID,
+			Status:  status,
+			Current: msg.ProgressDetail.Current,
+			Total:   msg.ProgressDetail.Total,
+		})
+	}
+}
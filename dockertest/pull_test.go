@@ -0,0 +1,123 @@
+package dockertest
+
+import (
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+
+	dc "github.com/fsouza/go-dockerclient"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMatchPlatform(t *testing.T) {
+	Convey("Given a linux/amd64 image", t, func() {
+		img := &dc.Image{OS: "linux", Architecture: "amd64"}
+
+		Convey("Should match platforms", func() {
+			So(matchPlatform(img, ""), ShouldBeTrue)
+			So(matchPlatform(img, "linux"), ShouldBeTrue)
+			So(matchPlatform(img, "linux/amd64"), ShouldBeTrue)
+			So(matchPlatform(img, "linux/arm64"), ShouldBeFalse)
+			So(matchPlatform(img, "windows/amd64"), ShouldBeFalse)
+		})
+	})
+}
+
+func TestParsePullPolicy(t *testing.T) {
+	Convey("Given pull policy names", t, func() {
+		Convey("Should accept known policies", func() {
+			for _, name := range []string{"always", "if-not-present", "never"} {
+				policy, err := ParsePullPolicy(name)
+				So(err, ShouldBeNil)
+				So(policy, ShouldEqual, PullPolicy(name))
+			}
+		})
+
+		Convey("Should reject unknown policies", func() {
+			_, err := ParsePullPolicy("alwayz")
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, `dockertest: unknown pull policy "alwayz"`)
+
+			pool := &Pool{}
+			err = pool.PullImages([]string{"redis:3"}, PullOptions{Policy: "alwayz"})
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestDecodeProgress(t *testing.T) {
+	Convey("Given a stream of progress messages", t, func() {
+		stream := `{"status":"Pulling from library/redis","id":"3"}
+{"status":"Downloading","progressDetail":{"current":100,"total":200},"id":"a1b2"}
+{"error":"unauthorized"}
+`
+		var progress []PullProgress
+		err := decodeProgress(
+			ioutil.NopCloser(strings.NewReader(stream)), "redis:3",
+			func(p PullProgress) { progress = append(progress, p) },
+		)
+
+		Convey("Should report every message", func() {
+			So(err, ShouldBeNil)
+			So(progress, ShouldResemble, []PullProgress{
+				{Image: "redis:3", Layer: "3", Status: "Pulling from library/redis"},
+				{Image: "redis:3", Layer: "a1b2", Status: "Downloading", Current: 100, Total: 200},
+				{Image: "redis:3", Status: "unauthorized"},
+			})
+		})
+	})
+}
+
+func TestPullImages(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping TestPullImages")
+	}
+
+	Convey("Given a new pool", t, func() {
+		pool, err := NewPool("")
+		So(err, ShouldBeNil)
+
+		pool.Client.RemoveImageExtended(testRemoteImage, dc.RemoveImageOptions{
+			Force: true,
+		})
+
+		Convey("When pulling with the never policy", func() {
+			err := pool.PullImages([]string{testRemoteImage}, PullOptions{
+				Policy: PullNever,
+			})
+
+			Convey("Should report a missing image", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When pulling missing and present images", func() {
+			var mu sync.Mutex
+			var messages int
+
+			err := pool.PullImages([]string{testRemoteImage, testLocalImage}, PullOptions{
+				Progress: func(PullProgress) {
+					mu.Lock()
+					messages++
+					mu.Unlock()
+				},
+			})
+
+			Convey("Should pull only the missing one", func() {
+				So(err, ShouldBeNil)
+				So(messages, ShouldBeGreaterThan, 0)
+
+				report := pool.Report()
+				So(report.PulledImages, ShouldResemble, []string{testRemoteImage})
+				So(report.CachedImages, ShouldResemble, []string{testLocalImage})
+			})
+		})
+
+		Reset(func() {
+			pool.Client.RemoveImageExtended(testRemoteImage, dc.RemoveImageOptions{
+				Force: true,
+			})
+		})
+	})
+}
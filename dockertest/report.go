@@ -0,0 +1,190 @@
+package dockertest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	dc "github.com/fsouza/go-dockerclient"
+)
+
+type (
+	// Timing is a duration of a single pool operation.
+	Timing struct {
+		Event    Event         `json:"event"`
+		Resource string        `json:"resource"`
+		Start    time.Time     `json:"start"`
+		Duration time.Duration `json:"duration"`
+		Error    string        `json:"error,omitempty"`
+	}
+
+	// Report contains timings of pool operations and images used
+	// by the pool. Use `Pool.Report()` to get it.
+	Report struct {
+		Timings      []Timing `json:"timings"`
+		PulledImages []string `json:"pulled_images"`
+		CachedImages []string `json:"cached_images"`
+	}
+)
+
+// resourceName returns a name of the resource the operation works on.
+func resourceName(op *Operation) string {
+	switch {
+	case op.Container != nil:
+		if name := strings.TrimPrefix(op.Container.Name, "/"); name != "" {
+			return name
+		}
+		return shortID(op.Container.ID)
+	case op.NetworkName != "":
+		return op.NetworkName
+	default:
+		return op.Image
+	}
+}
+
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+func (p *Pool) recordTiming(op *Operation) {
+	t := Timing{
+		Event:    op.Event,
+		Resource: resourceName(op),
+		Start:    op.started,
+		Duration: time.Since(op.started),
+	}
+	if op.Err != nil {
+		t.Error = op.Err.Error()
+	}
+
+	// Sub-pools report to their parents as well.
+	for q := p; q != nil; q = q.parent {
+		q.rw.Lock()
+		q.report.Timings = append(q.report.Timings, t)
+		q.rw.Unlock()
+	}
+}
+
+func (p *Pool) recordImage(image string, pulled bool) {
+	for q := p; q != nil; q = q.parent {
+		q.rw.Lock()
+		if pulled {
+			q.report.PulledImages = appendUnique(q.report.PulledImages, image)
+		} else {
+			q.report.CachedImages = appendUnique(q.report.CachedImages, image)
+		}
+		q.rw.Unlock()
+	}
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, item := range list {
+		if item == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+// Report returns timings of all operations done by the pool so far.
+// Images are reported as cached only if they were checked before
+// running a container, for example by `RunContainer()`.
+func (p *Pool) Report() *Report {
+	p.rw.RLock()
+	r := &Report{
+		Timings:      append([]Timing(nil), p.report.Timings...),
+		PulledImages: append([]string(nil), p.report.PulledImages...),
+		CachedImages: append([]string(nil), p.report.CachedImages...),
+	}
+	p.rw.RUnlock()
+
+	sort.Strings(r.PulledImages)
+	sort.Strings(r.CachedImages)
+
+	return r
+}
+
+// Total returns a sum of durations of operations with the event.
+func (r *Report) Total(event Event) time.Duration {
+	var total time.Duration
+	for _, t := range r.Timings {
+		if t.Event == event {
+			total += t.Duration
+		}
+	}
+	return total
+}
+
+// WriteJSON writes the report as JSON. Durations are in nanoseconds.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteText writes the report as a human-readable table followed
+// by totals per event and used images.
+func (r *Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "EVENT\tRESOURCE\tDURATION\tERROR")
+	for _, t := range r.Timings {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n",
+			t.Event, t.Resource, roundDuration(t.Duration), t.Error)
+	}
+
+	fmt.Fprintln(tw, "\nEVENT\tCOUNT\tTOTAL\t")
+	for _, event := range []Event{
+		EventPull, EventCreate, EventStart, EventReady, EventRestart,
+		EventPurge, EventCreateNetwork, EventPurgeNetwork,
+	} {
+		count := 0
+		for _, t := range r.Timings {
+			if t.Event == event {
+				count++
+			}
+		}
+		if count > 0 {
+			fmt.Fprintf(tw, "%s\t%d\t%s\t\n", event, count, roundDuration(r.Total(event)))
+		}
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "\nPulled images: %s\nCached images: %s\n",
+		joinOrNone(r.PulledImages), joinOrNone(r.CachedImages))
+	return err
+}
+
+// String returns the report in the human-readable form.
+func (r *Report) String() string {
+	var buf bytes.Buffer
+	r.WriteText(&buf)
+	return buf.String()
+}
+
+func roundDuration(d time.Duration) time.Duration {
+	return d / time.Millisecond * time.Millisecond
+}
+
+func joinOrNone(list []string) string {
+	if len(list) == 0 {
+		return "none"
+	}
+	return strings.Join(list, ", ")
+}
+
+// containerName is used by `Resource.String()`.
+func containerName(container *dc.Container) string {
+	return resourceName(&Operation{Container: container})
+}
@@ -0,0 +1,70 @@
+package dockertest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	dc "github.com/fsouza/go-dockerclient"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestReport(t *testing.T) {
+	Convey("Given a pool with recorded operations", t, func() {
+		pool := &Pool{}
+		started := time.Now().Add(-time.Second)
+
+		pool.recordImage("redis:3", true)
+		pool.recordImage("postgres:9.6", false)
+		pool.recordImage("postgres:9.6", false)
+		pool.after(&Operation{Event: EventPull, Image: "redis:3", started: started}, nil)
+		pool.after(&Operation{
+			Event:     EventStart,
+			Container: &dc.Container{ID: "0123456789abcdef", Name: "/db"},
+			started:   started,
+		}, nil)
+		pool.after(&Operation{
+			Event:     EventReady,
+			Container: &dc.Container{ID: "0123456789abcdef"},
+			started:   started,
+		}, errors.New("timeout"))
+
+		r := pool.Report()
+
+		Convey("Should contain timings", func() {
+			So(r.Timings, ShouldHaveLength, 3)
+			So(r.Timings[0].Event, ShouldEqual, EventPull)
+			So(r.Timings[0].Resource, ShouldEqual, "redis:3")
+			So(r.Timings[0].Duration, ShouldBeGreaterThanOrEqualTo, time.Second)
+			So(r.Timings[1].Resource, ShouldEqual, "db")
+			So(r.Timings[2].Resource, ShouldEqual, "0123456789ab")
+			So(r.Timings[2].Error, ShouldEqual, "timeout")
+			So(r.Total(EventStart), ShouldBeGreaterThanOrEqualTo, time.Second)
+		})
+
+		Convey("Should contain unique pulled and cached images", func() {
+			So(r.PulledImages, ShouldResemble, []string{"redis:3"})
+			So(r.CachedImages, ShouldResemble, []string{"postgres:9.6"})
+		})
+
+		Convey("Should be written as JSON", func() {
+			var buf bytes.Buffer
+			So(r.WriteJSON(&buf), ShouldBeNil)
+
+			var decoded Report
+			So(json.Unmarshal(buf.Bytes(), &decoded), ShouldBeNil)
+			So(decoded.Timings, ShouldHaveLength, 3)
+			So(decoded.PulledImages, ShouldResemble, []string{"redis:3"})
+		})
+
+		Convey("Should be written as text", func() {
+			text := r.String()
+			So(text, ShouldContainSubstring, "EVENT")
+			So(text, ShouldContainSubstring, "timeout")
+			So(text, ShouldContainSubstring, "Pulled images: redis:3")
+			So(text, ShouldContainSubstring, "Cached images: postgres:9.6")
+		})
+	})
+}
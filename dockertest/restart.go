@@ -0,0 +1,309 @@
+package dockertest
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	dc "github.com/fsouza/go-dockerclient"
+)
+
+// defaultStopTimeout is a number of seconds docker waits for
+// a container to stop before killing it.
+const defaultStopTimeout = 10
+
+// Restart restarts the container and updates the tracked container.
+// Docker keeps the container network settings and usually reuses
+// published host ports, but random host ports can be taken by another
+// process while the container is stopped. In that case the container
+// is recreated like by `Recreate()` with the old host ports, and
+// an error is returned if they can't be used. The returned container
+// can be a new one, so use it rather than the old one.
+func (p *Pool) Restart(container *dc.Container) (*dc.Container, error) {
+	op := &Operation{Event: EventRestart, Container: container}
+	if err := p.before(op); err != nil {
+		return nil, err
+	}
+
+	old, err := p.Client.InspectContainer(container.ID)
+	if err != nil {
+		return nil, p.after(op, err)
+	}
+
+	if err := p.Client.RestartContainer(container.ID, defaultStopTimeout); err != nil {
+		return nil, p.after(op, err)
+	}
+
+	restarted, err := p.Client.InspectContainer(container.ID)
+	if err != nil {
+		return nil, p.after(op, err)
+	}
+	p.updateContainer(restarted)
+
+	if !samePorts(old, restarted) {
+		moved := copyContainer(restarted)
+		moved.NetworkSettings.Ports = old.NetworkSettings.Ports
+		restarted, err = p.recreateContainer(moved, nil)
+	}
+	op.Container = restarted
+
+	return restarted, p.after(op, err)
+}
+
+// Recreate removes the container and creates a new one with the same
+// name, configuration, host ports, networks with aliases and volumes.
+// Anonymous volumes are kept, so data survives, and they are tracked
+// by the pool to be removed by `PurgeAll()`. The new container replaces
+// the old one in the pool.
+func (p *Pool) Recreate(container *dc.Container) (*dc.Container, error) {
+	return p.recreate(container, nil)
+}
+
+// recreate recreates the container and copies `files` into it before
+// it starts, like `ContainerBuilder.Run()` does.
+func (p *Pool) recreate(container *dc.Container, files []fileCopy) (*dc.Container, error) {
+	old, err := p.Client.InspectContainer(container.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.recreateContainer(old, files)
+}
+
+// recreateContainer recreates an inspected container with host ports
+// of its network settings.
+func (p *Pool) recreateContainer(old *dc.Container, files []fileCopy) (*dc.Container, error) {
+	opts, extra := p.recreateOptions(old)
+
+	if err := p.purgeContainer(old, false); err != nil {
+		return nil, err
+	}
+
+	created, err := p.createContainer(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) > 0 {
+		if err := uploadFiles(p.Client, created.ID, files); err != nil {
+			p.Client.RemoveContainer(dc.RemoveContainerOptions{
+				ID:    created.ID,
+				Force: true,
+			})
+			return nil, err
+		}
+	}
+
+	started, err := p.startContainer(created)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(extra) == 0 {
+		return started, nil
+	}
+
+	for _, name := range sortedKeys(extra) {
+		if err := p.Client.ConnectNetwork(name, dc.NetworkConnectionOptions{
+			Container:      started.ID,
+			EndpointConfig: extra[name],
+		}); err != nil {
+			return started, err
+		}
+	}
+
+	started, err = p.Client.InspectContainer(started.ID)
+	if err != nil {
+		return nil, err
+	}
+	p.updateContainer(started)
+
+	return started, nil
+}
+
+// recreateOptions returns options of a container equal to `old` and
+// endpoints of networks which must be connected after it starts.
+func (p *Pool) recreateOptions(
+	old *dc.Container,
+) (dc.CreateContainerOptions, map[string]*dc.EndpointConfig) {
+	config := *old.Config
+	hostConfig := dc.HostConfig{}
+	if old.HostConfig != nil {
+		hostConfig = *old.HostConfig
+	}
+	hostConfig.PortBindings = pinPorts(old)
+	hostConfig.Binds = append([]string(nil), hostConfig.Binds...)
+
+	// Keep anonymous volumes, for example declared by the image.
+	for _, m := range old.Mounts {
+		if m.Name == "" || hasMountTarget(&hostConfig, m.Destination) {
+			continue
+		}
+		hostConfig.Binds = append(hostConfig.Binds, m.Name+":"+m.Destination)
+
+		p.trackVolume(&dc.Volume{Name: m.Name, Driver: m.Driver})
+	}
+
+	opts := dc.CreateContainerOptions{
+		Name:       strings.TrimPrefix(old.Name, "/"),
+		Config:     &config,
+		HostConfig: &hostConfig,
+	}
+
+	// The container is connected to the network mode network when it's
+	// created and to other networks after it starts.
+	mode := hostConfig.NetworkMode
+	if mode == "" || mode == "default" {
+		mode = "bridge"
+	}
+
+	var extra map[string]*dc.EndpointConfig
+	if old.NetworkSettings != nil {
+		for name, n := range old.NetworkSettings.Networks {
+			endpoint := &dc.EndpointConfig{Aliases: userAliases(old, n.Aliases)}
+			if name == mode {
+				if len(endpoint.Aliases) == 0 {
+					continue
+				}
+				opts.NetworkingConfig = &dc.NetworkingConfig{
+					EndpointsConfig: map[string]*dc.EndpointConfig{name: endpoint},
+				}
+				continue
+			}
+			if extra == nil {
+				extra = make(map[string]*dc.EndpointConfig)
+			}
+			extra[name] = endpoint
+		}
+	}
+
+	return opts, extra
+}
+
+// trackVolume adds the volume to the pool unless it's already tracked.
+func (p *Pool) trackVolume(vol *dc.Volume) {
+	p.rw.Lock()
+	defer p.rw.Unlock()
+
+	for _, v := range p.Volumes {
+		if v.Name == vol.Name {
+			return
+		}
+	}
+	p.Volumes = append(p.Volumes, vol)
+}
+
+// pinPorts returns port bindings with host ports currently used
+// by the container.
+func pinPorts(container *dc.Container) map[dc.Port][]dc.PortBinding {
+	bindings := make(map[dc.Port][]dc.PortBinding)
+	if container.NetworkSettings == nil {
+		return bindings
+	}
+
+	for port, published := range container.NetworkSettings.Ports {
+		hostIP := ""
+		if container.HostConfig != nil {
+			if configured := container.HostConfig.PortBindings[port]; len(configured) > 0 {
+				hostIP = configured[0].HostIP
+			}
+		}
+
+		seen := make(map[string]bool)
+		for _, b := range published {
+			if b.HostPort == "" || seen[b.HostPort] {
+				continue
+			}
+			seen[b.HostPort] = true
+			bindings[port] = append(bindings[port], dc.PortBinding{HostIP: hostIP, HostPort: b.HostPort})
+		}
+	}
+
+	return bindings
+}
+
+// samePorts returns true if the restarted container uses host ports
+// of the old one. Containers which didn't publish ports are the same.
+func samePorts(old, restarted *dc.Container) bool {
+	ports := pinPorts(old)
+	return len(ports) == 0 || reflect.DeepEqual(ports, pinPorts(restarted))
+}
+
+// hasMountTarget returns true if a bind or a mount of `hostConfig`
+// is mounted at `target`.
+func hasMountTarget(hostConfig *dc.HostConfig, target string) bool {
+	for _, bind := range hostConfig.Binds {
+		parts := strings.Split(bind, ":")
+		if len(parts) > 1 && parts[1] == target {
+			return true
+		}
+	}
+	for _, m := range hostConfig.Mounts {
+		if m.Target == target {
+			return true
+		}
+	}
+	for dir := range hostConfig.Tmpfs {
+		if dir == target {
+			return true
+		}
+	}
+
+	return false
+}
+
+// userAliases removes the alias docker adds for the container ID.
+func userAliases(container *dc.Container, aliases []string) []string {
+	var result []string
+	for _, alias := range aliases {
+		if alias != shortID(container.ID) {
+			result = append(result, alias)
+		}
+	}
+	return result
+}
+
+func sortedKeys(m map[string]*dc.EndpointConfig) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Restart restarts the container. If `wait` is true, it waits until
+// the container is ready again using the readiness check and the
+// database given to the builder. Init scripts are not run again.
+func (r *Resource) Restart(wait bool) error {
+	container, err := r.pool.Restart(r.Container)
+	if container != nil {
+		r.Container = container
+	}
+	if err != nil || !wait || r.builder == nil {
+		return err
+	}
+
+	return r.builder.waitReady(r.pool, r, false)
+}
+
+// Recreate recreates the container keeping its ports, networks and
+// data. Files given to the builder with `WithFile()` are copied again.
+// If `wait` is true, it waits until the container is ready again like
+// `Restart()`.
+func (r *Resource) Recreate(wait bool) error {
+	var files []fileCopy
+	if r.builder != nil {
+		files = r.builder.files
+	}
+
+	container, err := r.pool.recreate(r.Container, files)
+	if container != nil {
+		r.Container = container
+	}
+	if err != nil || !wait || r.builder == nil {
+		return err
+	}
+
+	return r.builder.waitReady(r.pool, r, false)
+}
@@ -0,0 +1,165 @@
+package dockertest
+
+import (
+	"bytes"
+	"testing"
+
+	dc "github.com/fsouza/go-dockerclient"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRecreateOptions(t *testing.T) {
+	Convey("Given a container with published ports, networks and volumes", t, func() {
+		pool := &Pool{}
+		old := &dc.Container{
+			ID:     "0123456789abcdef",
+			Name:   "/db",
+			Config: &dc.Config{Image: "postgres:9.6"},
+			HostConfig: &dc.HostConfig{
+				NetworkMode:     "app",
+				PublishAllPorts: true,
+				Binds:           []string{"data:/backup"},
+				PortBindings: map[dc.Port][]dc.PortBinding{
+					"5432/tcp": {{HostIP: "127.0.0.1"}},
+				},
+			},
+			NetworkSettings: &dc.NetworkSettings{
+				Ports: map[dc.Port][]dc.PortBinding{
+					"5432/tcp": {
+						{HostIP: "127.0.0.1", HostPort: "32768"},
+					},
+					"8080/tcp": {
+						{HostIP: "0.0.0.0", HostPort: "32769"},
+						{HostIP: "::", HostPort: "32769"},
+					},
+				},
+				Networks: map[string]dc.ContainerNetwork{
+					"app":    {Aliases: []string{"db", "0123456789ab"}},
+					"backup": {Aliases: []string{"postgres"}},
+				},
+			},
+			Mounts: []dc.Mount{
+				{Name: "data", Destination: "/backup"},
+				{Name: "3f2a", Destination: "/var/lib/postgresql/data", Driver: "local"},
+				{Source: "/tmp", Destination: "/tmp"},
+			},
+		}
+
+		opts, extra := pool.recreateOptions(old)
+
+		Convey("Should pin host ports", func() {
+			So(opts.HostConfig.PortBindings, ShouldResemble, map[dc.Port][]dc.PortBinding{
+				"5432/tcp": {{HostIP: "127.0.0.1", HostPort: "32768"}},
+				"8080/tcp": {{HostPort: "32769"}},
+			})
+		})
+
+		Convey("Should detect moved host ports", func() {
+			moved := copyContainer(old)
+			moved.NetworkSettings.Ports["8080/tcp"] = []dc.PortBinding{
+				{HostIP: "0.0.0.0", HostPort: "32770"},
+			}
+
+			So(samePorts(old, copyContainer(old)), ShouldBeTrue)
+			So(samePorts(old, moved), ShouldBeFalse)
+			So(samePorts(&dc.Container{}, moved), ShouldBeTrue)
+		})
+
+		Convey("Should keep the name and network aliases", func() {
+			So(opts.Name, ShouldEqual, "db")
+			So(opts.NetworkingConfig.EndpointsConfig["app"].Aliases, ShouldResemble, []string{"db"})
+			So(extra, ShouldResemble, map[string]*dc.EndpointConfig{
+				"backup": {Aliases: []string{"postgres"}},
+			})
+		})
+
+		Convey("Should keep and track anonymous volumes", func() {
+			So(opts.HostConfig.Binds, ShouldResemble, []string{
+				"data:/backup",
+				"3f2a:/var/lib/postgresql/data",
+			})
+			So(old.HostConfig.Binds, ShouldHaveLength, 1)
+			So(pool.Volumes, ShouldHaveLength, 1)
+			So(pool.Volumes[0].Name, ShouldEqual, "3f2a")
+
+			pool.recreateOptions(old)
+			So(pool.Volumes, ShouldHaveLength, 1)
+		})
+	})
+
+	Convey("Given a container of a sub-pool with an anonymous volume", t, func() {
+		child := (&Pool{}).newChild("first")
+		old := &dc.Container{
+			Name:       "/first_db",
+			Config:     &dc.Config{Image: "postgres:9.6"},
+			HostConfig: &dc.HostConfig{NetworkMode: "host"},
+			Mounts: []dc.Mount{
+				{Name: "3f2aabcdef", Destination: "/var/lib/postgresql/data"},
+			},
+		}
+
+		opts, _ := child.recreateOptions(old)
+		child.scopeOptions(&opts)
+
+		Convey("Should keep the name of the volume", func() {
+			So(opts.Name, ShouldEqual, "first_db")
+			So(opts.HostConfig.Binds, ShouldResemble, []string{
+				"3f2aabcdef:/var/lib/postgresql/data",
+			})
+		})
+	})
+}
+
+func TestRestart(t *testing.T) {
+	Convey("Given a running container with a published port", t, func() {
+		pool, err := NewPool("")
+		So(err, ShouldBeNil)
+
+		container, err := pool.RunContainerWithOpts(dc.CreateContainerOptions{
+			Name: "restarted",
+			Config: &dc.Config{
+				Image:        testLocalImage,
+				ExposedPorts: map[dc.Port]struct{}{"8888/tcp": {}},
+			},
+			HostConfig: &dc.HostConfig{PublishAllPorts: true},
+		})
+		So(err, ShouldBeNil)
+		port := GetPort(container, "8888/tcp")
+
+		Convey("Should keep the port after restart", func() {
+			restarted, err := pool.Restart(container)
+			So(err, ShouldBeNil)
+			So(restarted.ID, ShouldEqual, container.ID)
+			So(GetPort(restarted, "8888/tcp"), ShouldEqual, port)
+		})
+
+		Convey("Should keep the name and port after recreate", func() {
+			recreated, err := pool.Recreate(container)
+			So(err, ShouldBeNil)
+			So(recreated.ID, ShouldNotEqual, container.ID)
+			So(recreated.Name, ShouldEqual, "/restarted")
+			So(GetPort(recreated, "8888/tcp"), ShouldEqual, port)
+			So(pool.Containers, ShouldHaveLength, 1)
+		})
+
+		Convey("Should copy builder files again after recreate", func() {
+			r, err := NewContainer(testLocalImage).
+				WithFile("testdata/init/schema.sql", "/tmp/schema.sql").
+				Run(pool)
+			So(err, ShouldBeNil)
+			So(r.Recreate(false), ShouldBeNil)
+
+			var buf bytes.Buffer
+			err = pool.Client.DownloadFromContainer(r.Container.ID, dc.DownloadFromContainerOptions{
+				Path:         "/tmp/schema.sql",
+				OutputStream: &buf,
+			})
+			So(err, ShouldBeNil)
+			So(buf.String(), ShouldContainSubstring, "schema.sql")
+		})
+
+		Reset(func() {
+			pool.PurgeAll()
+		})
+	})
+}
@@ -0,0 +1,237 @@
+package dockertest
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+)
+
+type (
+	// Policy returns a delay before the next attempt. `attempt` is
+	// a number of attempts done so far, starting from 1.
+	Policy interface {
+		Delay(attempt int) time.Duration
+	}
+
+	// ConstantPolicy waits the same interval between attempts.
+	ConstantPolicy struct {
+		Interval time.Duration
+	}
+
+	// ExponentialPolicy multiplies the delay after every attempt.
+	// Multiplier defaults to 2; values below 1 are treated as 1, so
+	// delays never shrink. Jitter is a randomization factor in range
+	// [0, 1]; a delay d becomes a random value between d*(1-Jitter)
+	// and d*(1+Jitter).
+	ExponentialPolicy struct {
+		Initial    time.Duration
+		Max        time.Duration
+		Multiplier float64
+		Jitter     float64
+	}
+
+	// FibonacciPolicy grows delays as the Fibonacci sequence
+	// multiplied by Initial.
+	FibonacciPolicy struct {
+		Initial time.Duration
+		Max     time.Duration
+	}
+
+	// RetryOptions configure `RetryWithOptions()`. Zero values mean
+	// no limit.
+	RetryOptions struct {
+		// Policy defaults to `DefaultPolicy`.
+		Policy Policy
+
+		MaxAttempts int
+
+		// MaxElapsed stops retrying if the next attempt would start
+		// after this time since the first one.
+		MaxElapsed time.Duration
+
+		// AttemptTimeout is a timeout of a single attempt. The context
+		// passed to the operation is cancelled after it passes.
+		AttemptTimeout time.Duration
+
+		// OnAttempt is called after every failed attempt with the delay
+		// before the next one. `next` is zero after the last attempt.
+		OnAttempt func(attempt int, err error, next time.Duration)
+	}
+
+	// PermanentError stops retrying. Use `Permanent()` to create one.
+	PermanentError struct {
+		Err error
+	}
+)
+
+// DefaultPolicy is used by `Retry()`.
+var DefaultPolicy Policy = ExponentialPolicy{
+	Initial:    500 * time.Millisecond,
+	Max:        5 * time.Second,
+	Multiplier: 1.5,
+	Jitter:     0.5,
+}
+
+// Delay implements `Policy`.
+func (p ConstantPolicy) Delay(attempt int) time.Duration {
+	return p.Interval
+}
+
+// Delay implements `Policy`. Without Max, delays are capped
+// at the longest `time.Duration`.
+func (p ExponentialPolicy) Delay(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	} else if multiplier < 1 {
+		multiplier = 1
+	}
+	max := float64(math.MaxInt64)
+	if p.Max > 0 {
+		max = float64(p.Max)
+	}
+
+	d := float64(p.Initial)
+	for i := 1; i < attempt; i++ {
+		d *= multiplier
+		if d > max {
+			d = max
+			break
+		}
+	}
+
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (2*rand.Float64() - 1)
+	}
+
+	// float64(math.MaxInt64) is rounded up, so it doesn't convert back.
+	if d >= float64(math.MaxInt64) {
+		return math.MaxInt64
+	}
+	return time.Duration(d)
+}
+
+// Delay implements `Policy`. Without Max, delays are capped
+// at the longest `time.Duration`.
+func (p FibonacciPolicy) Delay(attempt int) time.Duration {
+	if p.Initial <= 0 {
+		return 0
+	}
+	max := p.Max
+	if max <= 0 {
+		max = math.MaxInt64
+	}
+
+	// limit is the largest Fibonacci number whose delay fits in max.
+	limit := int64(max / p.Initial)
+	a, b := int64(0), int64(1)
+	for i := 0; i < attempt; i++ {
+		if b > limit {
+			return max
+		}
+		a, b = b, a+b
+		if b < 0 {
+			b = math.MaxInt64
+		}
+	}
+
+	return time.Duration(a) * p.Initial
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+// Permanent wraps `err`, so that retrying stops and `err` is returned.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+// Retry runs `op` every x seconds using exponential back-off strategy.
+// It's a short version of `RetryWithOptions()`.
+func Retry(maxWait time.Duration, op func() error) error {
+	return RetryWithOptions(
+		context.Background(),
+		RetryOptions{MaxElapsed: maxWait},
+		func(context.Context) error { return op() },
+	)
+}
+
+// RetryWithOptions runs `op` until it succeeds, returns a permanent
+// error, the limits in `opts` are reached or `ctx` is done. It returns
+// the last error of `op` or the context error.
+func RetryWithOptions(
+	ctx context.Context, opts RetryOptions, op func(context.Context) error,
+) error {
+	policy := opts.Policy
+	if policy == nil {
+		policy = DefaultPolicy
+	}
+	start := time.Now()
+
+	for attempt := 1; ; attempt++ {
+		err := runAttempt(ctx, opts.AttemptTimeout, op)
+		if err == nil {
+			return nil
+		}
+		if perm, ok := err.(*PermanentError); ok {
+			if opts.OnAttempt != nil {
+				opts.OnAttempt(attempt, perm.Err, 0)
+			}
+			return perm.Err
+		}
+
+		next := policy.Delay(attempt)
+		stop := opts.MaxAttempts > 0 && attempt >= opts.MaxAttempts ||
+			opts.MaxElapsed > 0 && time.Since(start)+next > opts.MaxElapsed
+		if stop {
+			next = 0
+		}
+		if opts.OnAttempt != nil {
+			opts.OnAttempt(attempt, err, next)
+		}
+		if stop {
+			return err
+		}
+
+		timer := time.NewTimer(next)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// runAttempt runs `op` with a timeout. If `op` ignores the context,
+// it's abandoned when the timeout passes.
+func runAttempt(
+	ctx context.Context, timeout time.Duration, op func(context.Context) error,
+) error {
+	if err := ctx.Err(); err != nil {
+		return Permanent(err)
+	}
+	if timeout <= 0 {
+		return op(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- op(ctx)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,155 @@
+package dockertest
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRetryPolicies(t *testing.T) {
+	Convey("Given a constant policy", t, func() {
+		p := ConstantPolicy{Interval: time.Second}
+
+		Convey("Should always return the interval", func() {
+			So(p.Delay(1), ShouldEqual, time.Second)
+			So(p.Delay(10), ShouldEqual, time.Second)
+		})
+	})
+
+	Convey("Given an exponential policy without jitter", t, func() {
+		p := ExponentialPolicy{Initial: time.Second, Max: 5 * time.Second, Multiplier: 2}
+
+		Convey("Should double delays up to the max", func() {
+			So(p.Delay(1), ShouldEqual, time.Second)
+			So(p.Delay(2), ShouldEqual, 2*time.Second)
+			So(p.Delay(3), ShouldEqual, 4*time.Second)
+			So(p.Delay(4), ShouldEqual, 5*time.Second)
+			So(p.Delay(100), ShouldEqual, 5*time.Second)
+		})
+	})
+
+	Convey("Given exponential policies with invalid multipliers", t, func() {
+		Convey("Should default to doubling delays", func() {
+			p := ExponentialPolicy{Initial: time.Second}
+			So(p.Delay(3), ShouldEqual, 4*time.Second)
+		})
+
+		Convey("Should not shrink delays", func() {
+			p := ExponentialPolicy{Initial: time.Second, Multiplier: 0.5}
+			So(p.Delay(3), ShouldEqual, time.Second)
+		})
+	})
+
+	Convey("Given an exponential policy with jitter", t, func() {
+		p := ExponentialPolicy{Initial: time.Second, Multiplier: 2, Jitter: 0.5}
+
+		Convey("Should randomize delays", func() {
+			for i := 0; i < 100; i++ {
+				So(p.Delay(2), ShouldBeBetweenOrEqual, time.Second, 3*time.Second)
+			}
+		})
+	})
+
+	Convey("Given a fibonacci policy", t, func() {
+		p := FibonacciPolicy{Initial: time.Second, Max: 10 * time.Second}
+
+		Convey("Should return the sequence up to the max", func() {
+			var delays []time.Duration
+			for i := 1; i <= 7; i++ {
+				delays = append(delays, p.Delay(i)/time.Second)
+			}
+			So(delays, ShouldResemble, []time.Duration{1, 1, 2, 3, 5, 8, 10})
+		})
+	})
+
+	Convey("Given policies without a max", t, func() {
+		Convey("Should cap delays instead of overflowing", func() {
+			exp := ExponentialPolicy{Initial: time.Second, Jitter: 0.5}
+			fib := FibonacciPolicy{Initial: time.Second}
+			for _, attempt := range []int{100, 1000} {
+				So(exp.Delay(attempt), ShouldBeGreaterThan, 0)
+				So(fib.Delay(attempt), ShouldEqual, time.Duration(math.MaxInt64))
+			}
+			So(ExponentialPolicy{Initial: time.Second}.Delay(100), ShouldEqual, time.Duration(math.MaxInt64))
+			So(FibonacciPolicy{Initial: time.Nanosecond}.Delay(200), ShouldEqual, time.Duration(math.MaxInt64))
+		})
+	})
+}
+
+func TestRetryWithOptions(t *testing.T) {
+	fast := ConstantPolicy{Interval: time.Millisecond}
+	errNotReady := errors.New("not ready")
+
+	Convey("Given max attempts", t, func() {
+		var attempts []int
+		err := RetryWithOptions(context.Background(), RetryOptions{
+			Policy:      fast,
+			MaxAttempts: 3,
+			OnAttempt: func(attempt int, err error, next time.Duration) {
+				attempts = append(attempts, attempt)
+			},
+		}, func(context.Context) error { return errNotReady })
+
+		Convey("Should stop after them and return the last error", func() {
+			So(err, ShouldEqual, errNotReady)
+			So(attempts, ShouldResemble, []int{1, 2, 3})
+		})
+	})
+
+	Convey("Given a permanent error", t, func() {
+		calls := 0
+		err := RetryWithOptions(context.Background(), RetryOptions{Policy: fast},
+			func(context.Context) error {
+				calls++
+				return Permanent(errNotReady)
+			})
+
+		Convey("Should stop immediately", func() {
+			So(err, ShouldEqual, errNotReady)
+			So(calls, ShouldEqual, 1)
+		})
+	})
+
+	Convey("Given a cancelled context", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		calls := 0
+		err := RetryWithOptions(ctx, RetryOptions{Policy: fast},
+			func(context.Context) error {
+				calls++
+				if calls == 2 {
+					cancel()
+				}
+				return errNotReady
+			})
+
+		Convey("Should stop with the context error", func() {
+			So(err, ShouldEqual, context.Canceled)
+			So(calls, ShouldEqual, 2)
+		})
+	})
+
+	Convey("Given an attempt timeout", t, func() {
+		// Attempts run in their own goroutines.
+		var calls int32
+		err := RetryWithOptions(context.Background(), RetryOptions{
+			Policy:         fast,
+			AttemptTimeout: 10 * time.Millisecond,
+		}, func(ctx context.Context) error {
+			if atomic.AddInt32(&calls, 1) < 3 {
+				<-ctx.Done()
+				return ctx.Err()
+			}
+			return nil
+		})
+
+		Convey("Should retry timed out attempts", func() {
+			So(err, ShouldBeNil)
+			So(atomic.LoadInt32(&calls), ShouldEqual, 3)
+		})
+	})
+}
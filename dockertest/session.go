@@ -0,0 +1,186 @@
+package dockertest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	dc "github.com/fsouza/go-dockerclient"
+)
+
+// Labels set on every resource created by a pool.
+const (
+	LabelSession = "dockertest.session"
+	LabelCreated = "dockertest.created"
+)
+
+// Resources is a list of docker resources found by labels.
+type Resources struct {
+	Containers []dc.APIContainers
+	Networks   []dc.Network
+	Volumes    []*dc.Volume
+}
+
+func newSessionID() string {
+	if os.Getenv("DOCKERTEST_SESSION") != "" {
+		return os.Getenv("DOCKERTEST_SESSION")
+	}
+
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// labels returns `labels` extended with the pool session labels.
+// `labels` is not modified.
+func (p *Pool) labels(labels map[string]string) map[string]string {
+	result := make(map[string]string, len(labels)+2)
+	for k, v := range labels {
+		result[k] = v
+	}
+	result[LabelSession] = p.Session
+	result[LabelCreated] = strconv.FormatInt(time.Now().Unix(), 10)
+	if p.name != "" {
+		result[LabelPool] = p.name
+	}
+
+	return result
+}
+
+// CreatedAt returns time when the resource with `labels` was created
+// by a pool. Zero time is returned if the label is missing.
+func CreatedAt(labels map[string]string) time.Time {
+	sec, err := strconv.ParseInt(labels[LabelCreated], 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return time.Unix(sec, 0)
+}
+
+// ListResources returns all resources created by pools in `session`.
+// If `session` is empty, resources from all sessions are returned.
+func (p *Pool) ListResources(session string) (*Resources, error) {
+	label := LabelSession
+	if session != "" {
+		label += "=" + session
+	}
+
+	containers, err := p.Client.ListContainers(dc.ListContainersOptions{
+		All:     true,
+		Filters: map[string][]string{"label": {label}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	networks, err := p.Client.FilteredListNetworks(dc.NetworkFilterOpts{
+		"label": {label: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	volumes, err := p.Client.ListVolumes(dc.ListVolumesOptions{
+		Filters: map[string][]string{"label": {label}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Resources{
+		Containers: containers,
+		Networks:   networks,
+	}
+	for i := range volumes {
+		res.Volumes = append(res.Volumes, &volumes[i])
+	}
+
+	return res, nil
+}
+
+// OlderThan returns resources created more than `d` ago.
+func (r *Resources) OlderThan(d time.Duration) *Resources {
+	deadline := time.Now().Add(-d)
+	filtered := &Resources{}
+
+	for _, c := range r.Containers {
+		if CreatedAt(c.Labels).Before(deadline) {
+			filtered.Containers = append(filtered.Containers, c)
+		}
+	}
+	for _, n := range r.Networks {
+		if CreatedAt(n.Labels).Before(deadline) {
+			filtered.Networks = append(filtered.Networks, n)
+		}
+	}
+	for _, v := range r.Volumes {
+		if CreatedAt(v.Labels).Before(deadline) {
+			filtered.Volumes = append(filtered.Volumes, v)
+		}
+	}
+
+	return filtered
+}
+
+// PurgeResources removes resources found by `ListResources()`.
+// Containers are removed first, then networks and volumes.
+func (p *Pool) PurgeResources(r *Resources) error {
+	var wg sync.WaitGroup
+	errCh := make(chan error, len(r.Containers)+len(r.Networks)+len(r.Volumes))
+
+	for _, c := range r.Containers {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			if err := p.Client.RemoveContainer(dc.RemoveContainerOptions{
+				ID:            id,
+				Force:         true,
+				RemoveVolumes: true,
+			}); err != nil {
+				errCh <- err
+			}
+		}(c.ID)
+	}
+	wg.Wait()
+
+	for _, n := range r.Networks {
+		if err := p.Client.RemoveNetwork(n.ID); err != nil {
+			errCh <- err
+		}
+	}
+	for _, v := range r.Volumes {
+		if err := p.Client.RemoveVolume(v.Name); err != nil {
+			errCh <- err
+		}
+	}
+
+	close(errCh)
+	if err := <-errCh; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Logs writes logs of the container to `w`. `tail` is a number of lines
+// from the end of logs or "all". If `follow` is true, it blocks until
+// the container stops.
+func (p *Pool) Logs(w io.Writer, id string, tail string, follow bool) error {
+	return p.Client.Logs(dc.LogsOptions{
+		Container:    id,
+		OutputStream: w,
+		ErrorStream:  w,
+		Stdout:       true,
+		Stderr:       true,
+		Tail:         tail,
+		Follow:       follow,
+	})
+}
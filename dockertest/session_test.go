@@ -0,0 +1,90 @@
+package dockertest
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	dc "github.com/fsouza/go-dockerclient"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSessionLabels(t *testing.T) {
+	Convey("Given a pool with a session", t, func() {
+		pool := &Pool{Session: "abc"}
+
+		Convey("Should add session labels without modifying the input", func() {
+			in := map[string]string{"app": "test"}
+			labels := pool.labels(in)
+
+			So(labels["app"], ShouldEqual, "test")
+			So(labels[LabelSession], ShouldEqual, "abc")
+			So(CreatedAt(labels), ShouldHappenWithin, time.Second, time.Now())
+			So(len(in), ShouldEqual, 1)
+		})
+	})
+
+	Convey("Given labels without a created label", t, func() {
+		Convey("Should return zero time", func() {
+			So(CreatedAt(nil).IsZero(), ShouldBeTrue)
+		})
+	})
+}
+
+func TestResourcesOlderThan(t *testing.T) {
+	Convey("Given old and new resources", t, func() {
+		old := map[string]string{
+			LabelCreated: strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10),
+		}
+		recent := map[string]string{
+			LabelCreated: strconv.FormatInt(time.Now().Unix(), 10),
+		}
+		res := &Resources{
+			Containers: []dc.APIContainers{{ID: "old", Labels: old}, {ID: "new", Labels: recent}},
+			Networks:   []dc.Network{{ID: "old", Labels: old}, {ID: "new", Labels: recent}},
+			Volumes:    []*dc.Volume{{Name: "old", Labels: old}, {Name: "new", Labels: recent}},
+		}
+
+		Convey("Should return only the old ones", func() {
+			filtered := res.OlderThan(10 * time.Minute)
+			So(len(filtered.Containers), ShouldEqual, 1)
+			So(filtered.Containers[0].ID, ShouldEqual, "old")
+			So(len(filtered.Networks), ShouldEqual, 1)
+			So(filtered.Networks[0].ID, ShouldEqual, "old")
+			So(len(filtered.Volumes), ShouldEqual, 1)
+			So(filtered.Volumes[0].Name, ShouldEqual, "old")
+		})
+	})
+}
+
+func TestListResources(t *testing.T) {
+	Convey("Given a pool with a container and a network", t, func() {
+		pool, err := NewPool("")
+		So(err, ShouldBeNil)
+
+		_, err = pool.CreateNetwork("test-session-net")
+		So(err, ShouldBeNil)
+		_, err = pool.RunContainer(testLocalImage, nil, false)
+		So(err, ShouldBeNil)
+
+		Convey("Should list them by session", func() {
+			res, err := pool.ListResources(pool.Session)
+			So(err, ShouldBeNil)
+			So(len(res.Containers), ShouldEqual, 1)
+			So(len(res.Networks), ShouldEqual, 1)
+
+			Convey("Should purge them", func() {
+				So(pool.PurgeResources(res), ShouldBeNil)
+
+				res, err := pool.ListResources(pool.Session)
+				So(err, ShouldBeNil)
+				So(len(res.Containers), ShouldEqual, 0)
+				So(len(res.Networks), ShouldEqual, 0)
+			})
+		})
+
+		Reset(func() {
+			pool.PurgeAll()
+		})
+	})
+}
@@ -0,0 +1,109 @@
+package dockertest
+
+import (
+	"strings"
+
+	dc "github.com/fsouza/go-dockerclient"
+)
+
+// Mount types supported by `Mount`.
+const (
+	MountVolume = "volume"
+	MountBind   = "bind"
+	MountTmpfs  = "tmpfs"
+)
+
+type (
+	// Mount describes a volume, bind or tmpfs mount in a container.
+	Mount struct {
+		// Type is one of `MountVolume`, `MountBind` or `MountTmpfs`.
+		Type string
+
+		// Source is a volume name or a host path. It's ignored for tmpfs.
+		Source string
+
+		// Target is a path in the container.
+		Target string
+
+		// ReadOnly mounts the source in read-only mode.
+		ReadOnly bool
+
+		// Options is a comma separated list of tmpfs options,
+		// for example "rw,size=64m".
+		Options string
+	}
+
+	// Mounts is a list of `Mount`.
+	Mounts []Mount
+)
+
+// VolumeMount returns a mount of a named volume.
+func VolumeMount(name, target string) Mount {
+	return Mount{Type: MountVolume, Source: name, Target: target}
+}
+
+// BindMount returns a mount of a host path.
+func BindMount(source, target string) Mount {
+	return Mount{Type: MountBind, Source: source, Target: target}
+}
+
+// TmpfsMount returns a tmpfs mount. `options` can be empty.
+func TmpfsMount(target, options string) Mount {
+	return Mount{Type: MountTmpfs, Target: target, Options: options}
+}
+
+// Apply adds mounts to the host config.
+func (m Mounts) Apply(hostConfig *dc.HostConfig) {
+	for _, mount := range m {
+		switch mount.Type {
+		case MountTmpfs:
+			if hostConfig.Tmpfs == nil {
+				hostConfig.Tmpfs = make(map[string]string)
+			}
+			hostConfig.Tmpfs[mount.Target] = mount.Options
+		default:
+			bind := []string{mount.Source, mount.Target}
+			if mount.ReadOnly {
+				bind = append(bind, "ro")
+			}
+			hostConfig.Binds = append(hostConfig.Binds, strings.Join(bind, ":"))
+		}
+	}
+}
+
+// CreateVolume creates a new named volume in the docker.
+func (p *Pool) CreateVolume(name string) (*dc.Volume, error) {
+	vol, err := p.Client.CreateVolume(dc.CreateVolumeOptions{
+		Name:   p.scopedName(name),
+		Labels: p.labels(nil),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p.rw.Lock()
+	p.Volumes = append(p.Volumes, vol)
+	p.rw.Unlock()
+
+	return vol, nil
+}
+
+// PurgeVolume removes volume from the docker.
+func (p *Pool) PurgeVolume(vol *dc.Volume) error {
+	err := p.Client.RemoveVolume(vol.Name)
+	if err != nil {
+		return err
+	}
+
+	p.rw.Lock()
+	vols := make([]*dc.Volume, 0, len(p.Volumes))
+	for _, v := range p.Volumes {
+		if v.Name != vol.Name {
+			vols = append(vols, v)
+		}
+	}
+	p.Volumes = vols
+	p.rw.Unlock()
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package dockertest
+
+import (
+	"testing"
+
+	dc "github.com/fsouza/go-dockerclient"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMountsApply(t *testing.T) {
+	Convey("Given volume, bind and tmpfs mounts", t, func() {
+		mounts := Mounts{
+			VolumeMount("test-vol", "/data"),
+			BindMount("/tmp", "/host-tmp"),
+			TmpfsMount("/run", "rw,size=64m"),
+		}
+		mounts[1].ReadOnly = true
+
+		Convey("Should add them to the host config", func() {
+			hostConfig := &dc.HostConfig{}
+			mounts.Apply(hostConfig)
+
+			So(hostConfig.Binds, ShouldResemble, []string{
+				"test-vol:/data",
+				"/tmp:/host-tmp:ro",
+			})
+			So(hostConfig.Tmpfs, ShouldResemble, map[string]string{
+				"/run": "rw,size=64m",
+			})
+		})
+	})
+}
+
+func TestCreateVolume(t *testing.T) {
+	Convey("Given a new pool", t, func() {
+		pool, err := NewPool("")
+		So(err, ShouldBeNil)
+
+		Convey("After creating a new volume", func() {
+			vol, err := pool.CreateVolume("test-vol")
+			So(err, ShouldBeNil)
+			So(len(pool.Volumes), ShouldEqual, 1)
+			So(pool.Volumes[0].Name, ShouldEqual, "test-vol")
+
+			Convey("Should be able to mount it in a container", func() {
+				container, err := pool.RunContainer(
+					testLocalImage, nil, false,
+					VolumeMount(vol.Name, "/data"),
+					TmpfsMount("/run/test", ""),
+				)
+				So(err, ShouldBeNil)
+				So(container.HostConfig.Binds, ShouldContain, "test-vol:/data")
+			})
+
+			Convey("Should be able to purge the volume", func() {
+				err := pool.PurgeVolume(vol)
+				So(err, ShouldBeNil)
+				So(len(pool.Volumes), ShouldEqual, 0)
+
+				_, err = pool.Client.InspectVolume(vol.Name)
+				So(err, ShouldNotBeNil)
+			})
+
+			Convey("Should purge it with PurgeAll", func() {
+				err := pool.PurgeAll()
+				So(err, ShouldBeNil)
+				So(len(pool.Volumes), ShouldEqual, 0)
+			})
+		})
+
+		Reset(func() {
+			pool.PurgeAll()
+		})
+	})
+}
@@ -0,0 +1,36 @@
+// Package yamlutil converts values decoded from YAML, so they can be
+// handled like values decoded from JSON.
+package yamlutil
+
+import "fmt"
+
+// Convert replaces YAML maps, which have keys of any type, with JSON
+// objects in `v` and its lists. Non-string keys are an error.
+func Convert(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			k, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("unsupported key %v", key)
+			}
+			converted, err := Convert(value)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = converted
+		}
+		return m, nil
+	case []interface{}:
+		for i, value := range v {
+			converted, err := Convert(value)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = converted
+		}
+	}
+
+	return v, nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"flag"
+	"log"
 	"net/http"
 
 	"github.com/adambabik/go-collections/config"
@@ -13,16 +14,66 @@ var (
 	configFilename = flag.String("config", "", "a filename with config")
 )
 
-func init() {
+func main() {
+	c := config.DefaultConfig
+	if err := config.BindFlags(flag.CommandLine, &c); err != nil {
+		log.Fatal(err)
+	}
 	flag.Parse()
-}
 
-func main() {
-	c := config.NewConfigFromFile(*configFilename)
-	config.UpdateFromEnv(c)
+	opts := []config.Option{
+		config.WithFile(*configFilename),
+		config.WithFlags(flag.CommandLine),
+	}
+
+	// current returns the config, which is reloaded when its file
+	// changes. Reloaded configs are swapped atomically by the watcher.
+	var (
+		current func() *config.Config
+		w       *config.Watcher
+	)
+	if *configFilename == "" {
+		p := config.Provenance{}
+		if err := config.Load(&c, append(opts, config.WithProvenance(p))...); err != nil {
+			log.Fatal(err)
+		}
+		if c.Debug {
+			log.Print("config:\n" + config.Dump(&c, p))
+		}
+		current = func() *config.Config { return &c }
+	} else {
+		// Flags are bound to `c`, so the watcher loads a fresh config
+		// instead of using values of `c` as defaults.
+		wc := config.DefaultConfig
+		var err error
+		if w, err = config.Watch(&wc, opts...); err != nil {
+			log.Fatal(err)
+		}
+
+		w.Subscribe(func(change config.Change) {
+			for _, f := range change.Fields {
+				log.Printf("config: %s changed from %q to %q", f.Field, f.Old, f.New)
+			}
+		})
+		current = func() *config.Config { return w.Current().(*config.Config) }
+		if wc.Debug {
+			log.Print("config:\n" + config.Dump(&wc, nil))
+		}
+	}
 
 	e := echo.New()
-	e.Debug = c.Debug
+	if w == nil {
+		e.Debug = c.Debug
+	}
+
+	// Debug can change on reloads, so details of errors depend on the
+	// current config instead of `e.Debug`, which echo reads unlocked.
+	e.HTTPErrorHandler = func(err error, c echo.Context) {
+		if _, ok := err.(*echo.HTTPError); !ok && current().Debug {
+			err = echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		e.DefaultHTTPErrorHandler(err, c)
+	}
 
 	e.Use(middleware.Logger())
 
@@ -31,5 +82,11 @@ func main() {
 	})
 
 	e.Logger.Info("Running server on :8888")
-	e.Logger.Fatal(e.Start(":8888"))
+	err := e.Start(":8888")
+	// Fatal exits without running deferred calls, so the watcher
+	// is closed first.
+	if w != nil {
+		w.Close()
+	}
+	e.Logger.Fatal(err)
 }